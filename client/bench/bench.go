@@ -0,0 +1,294 @@
+package bench
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/AdeMQ/client/remote"
+	"github.com/AdeMQ/protocol/message"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 消息内容的前8个字节为发送时间(纳秒), 用于计算端到端延迟
+const stampSize = 8
+
+// Config 压测配置
+type Config struct {
+	Topic     string        // 压测使用的主题
+	Producers int           // 生产者连接数, 默认 1
+	Consumers int           // 消费者连接数, 每个消费者都订阅主题并收到全部消息, 默认 1
+	Size      int           // 消息大小, 单位 字节, 默认 128, 最小为 8
+	Rate      int           // 所有生产者合计每秒发送的消息数, 0 表示不限速
+	Batch     int           // 每个请求发送的消息数, 大于1时使用 mpublish, 默认 1
+	Duration  time.Duration // 发送的持续时间, 默认 10s
+	Drain     time.Duration // 发送结束之后等待消费者接收剩余消息的最长时间, 默认 2s
+}
+
+func (c *Config) init() {
+	if c.Topic == "" {
+		c.Topic = fmt.Sprintf("bench-%d", time.Now().Unix())
+	}
+	if c.Producers <= 0 {
+		c.Producers = 1
+	}
+	if c.Consumers < 0 {
+		c.Consumers = 0
+	}
+	if c.Size < stampSize {
+		c.Size = stampSize
+	}
+	if c.Batch <= 0 {
+		c.Batch = 1
+	}
+	if c.Duration <= 0 {
+		c.Duration = 10 * time.Second
+	}
+	if c.Drain <= 0 {
+		c.Drain = 2 * time.Second
+	}
+}
+
+// Dialer 建立压测使用的连接
+type Dialer func(ctx context.Context, opts ...remote.Option) (*remote.Remote, error)
+
+// Latency 端到端延迟统计, 单位 毫秒
+type Latency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p999"`
+	Max  float64 `json:"max"`
+}
+
+// Result 压测结果, 字段使用 JSON 输出便于比较多次压测的结果
+type Result struct {
+	Topic        string  `json:"topic"`
+	Producers    int     `json:"producers"`
+	Consumers    int     `json:"consumers"`
+	Size         int     `json:"size"`
+	Rate         int     `json:"rate"`
+	Batch        int     `json:"batch"`
+	Elapsed      float64 `json:"elapsed_sec"`
+	Sent         int64   `json:"sent"`
+	SendErrors   int64   `json:"send_errors"`
+	Received     int64   `json:"received"`
+	Lost         int64   `json:"lost"` // 发送成功但是等待结束时消费者仍未收到的消息数, 多个消费者分别计算
+	SendMsgRate  float64 `json:"send_msgs_per_sec"`
+	SendMBRate   float64 `json:"send_mb_per_sec"`
+	RecvMsgRate  float64 `json:"recv_msgs_per_sec"`
+	RecvMBRate   float64 `json:"recv_mb_per_sec"`
+	LatencyMs    Latency `json:"latency_ms"`
+	LatencyCount int64   `json:"latency_samples"`
+}
+
+// consumer 压测的消费者, 推送回调在连接的读取协程中执行
+type consumer struct {
+	topic    string
+	mu       sync.Mutex
+	hist     *Histogram
+	received *int64
+}
+
+func (c *consumer) onPush(msg *message.Message) {
+	if msg.Topic != c.topic || len(msg.Payload) < stampSize {
+		return
+	}
+	latency := time.Now().UnixNano() - int64(binary.BigEndian.Uint64(msg.Payload))
+	c.mu.Lock()
+	c.hist.Record(latency)
+	c.mu.Unlock()
+	atomic.AddInt64(c.received, 1)
+}
+
+// runner 一次压测的状态
+type runner struct {
+	cfg      Config
+	conns    []*remote.Remote
+	sent     int64
+	errs     int64
+	received int64
+}
+
+// Run 执行压测: 建立消费者连接并订阅主题, 之后所有生产者按照速率发送消息直到持续时间结束
+// ctx 结束时提前停止发送并返回已经统计的结果, progress 不为 nil 时每秒调用一次
+func Run(ctx context.Context, cfg Config, dial Dialer, progress func(elapsed time.Duration, sent, received int64)) (*Result, error) {
+	cfg.init()
+	r := &runner{cfg: cfg}
+	defer r.close()
+
+	// 压测使用独立的连接, 不自动重连, 不发送心跳
+	opts := []remote.Option{remote.WithoutReconnect(), remote.WithHeartbeat(0)}
+	consumers := make([]*consumer, cfg.Consumers)
+	for i := range consumers {
+		c := &consumer{topic: cfg.Topic, hist: NewHistogram(), received: &r.received}
+		conn, err := r.dial(ctx, dial, append(opts, remote.WithPushHandler(c.onPush))...)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := conn.Call(ctx, &message.Request{Cmd: "subscribe", Params: []string{cfg.Topic}})
+		if err == nil && resp.Code != message.CodeOK {
+			err = errors.New(resp.Msg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("订阅主题失败: %v", err)
+		}
+		consumers[i] = c
+	}
+	producers := make([]*remote.Remote, cfg.Producers)
+	for i := range producers {
+		conn, err := r.dial(ctx, dial, opts...)
+		if err != nil {
+			return nil, err
+		}
+		producers[i] = conn
+	}
+
+	start := time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, conn := range producers {
+		wg.Add(1)
+		go func(conn *remote.Remote) {
+			defer wg.Done()
+			r.produce(runCtx, conn, start)
+		}(conn)
+	}
+	if progress != nil {
+		go r.report(runCtx, start, progress)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// 等待消费者接收发送结束之前已经写入的消息, ctx 结束时不再等待
+	expected := atomic.LoadInt64(&r.sent) * int64(cfg.Consumers)
+	deadline := time.Now().Add(cfg.Drain)
+	for ctx.Err() == nil && atomic.LoadInt64(&r.received) < expected && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.close()
+
+	hist := NewHistogram()
+	for _, c := range consumers {
+		c.mu.Lock()
+		hist.Merge(c.hist)
+		c.mu.Unlock()
+	}
+	return r.result(elapsed, hist), nil
+}
+
+func (r *runner) dial(ctx context.Context, dial Dialer, opts ...remote.Option) (*remote.Remote, error) {
+	conn, err := dial(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("建立压测连接失败: %v", err)
+	}
+	r.conns = append(r.conns, conn)
+	return conn, nil
+}
+
+// produce 生产者协程, 限速时按照固定的间隔发送, 落后时立即发送追赶进度
+func (r *runner) produce(ctx context.Context, conn *remote.Remote, start time.Time) {
+	var interval time.Duration
+	if r.cfg.Rate > 0 {
+		interval = time.Duration(float64(time.Second) * float64(r.cfg.Producers*r.cfg.Batch) / float64(r.cfg.Rate))
+	}
+	filler := make([]byte, r.cfg.Size-stampSize)
+	for i := range filler {
+		filler[i] = 'x'
+	}
+	for n := 0; ctx.Err() == nil; n++ {
+		if interval > 0 {
+			if wait := time.Until(start.Add(time.Duration(n) * interval)); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		payloads := make([][]byte, r.cfg.Batch)
+		for i := range payloads {
+			p := make([]byte, r.cfg.Size)
+			binary.BigEndian.PutUint64(p, uint64(time.Now().UnixNano()))
+			copy(p[stampSize:], filler)
+			payloads[i] = p
+		}
+		req := &message.Request{Cmd: "publish", Params: []string{r.cfg.Topic}, Payload: payloads[0]}
+		if r.cfg.Batch > 1 {
+			req = &message.Request{Cmd: "mpublish", Params: []string{r.cfg.Topic}, Batch: payloads}
+		}
+		// 请求使用默认的超时时间, 持续时间结束时正在发送的请求仍然等待完成
+		resp, err := conn.Call(context.Background(), req)
+		if err != nil || resp.Code != message.CodeOK {
+			atomic.AddInt64(&r.errs, int64(r.cfg.Batch))
+			continue
+		}
+		atomic.AddInt64(&r.sent, int64(r.cfg.Batch))
+	}
+}
+
+// report 每秒汇报一次进度
+func (r *runner) report(ctx context.Context, start time.Time, progress func(time.Duration, int64, int64)) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			progress(time.Since(start), atomic.LoadInt64(&r.sent), atomic.LoadInt64(&r.received))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *runner) close() {
+	for _, conn := range r.conns {
+		conn.Close()
+	}
+	r.conns = nil
+}
+
+func (r *runner) result(elapsed time.Duration, hist *Histogram) *Result {
+	sec := elapsed.Seconds()
+	sent, received := atomic.LoadInt64(&r.sent), atomic.LoadInt64(&r.received)
+	lost := sent*int64(r.cfg.Consumers) - received
+	if lost < 0 {
+		lost = 0
+	}
+	mb := float64(r.cfg.Size) / (1 << 20)
+	ms := func(ns int64) float64 {
+		return float64(ns) / float64(time.Millisecond)
+	}
+	return &Result{
+		Topic:       r.cfg.Topic,
+		Producers:   r.cfg.Producers,
+		Consumers:   r.cfg.Consumers,
+		Size:        r.cfg.Size,
+		Rate:        r.cfg.Rate,
+		Batch:       r.cfg.Batch,
+		Elapsed:     sec,
+		Sent:        sent,
+		SendErrors:  atomic.LoadInt64(&r.errs),
+		Received:    received,
+		Lost:        lost,
+		SendMsgRate: float64(sent) / sec,
+		SendMBRate:  float64(sent) * mb / sec,
+		RecvMsgRate: float64(received) / sec,
+		RecvMBRate:  float64(received) * mb / sec,
+		LatencyMs: Latency{
+			Min:  ms(hist.Min()),
+			Mean: hist.Mean() / float64(time.Millisecond),
+			P50:  ms(hist.Percentile(50)),
+			P90:  ms(hist.Percentile(90)),
+			P99:  ms(hist.Percentile(99)),
+			P999: ms(hist.Percentile(99.9)),
+			Max:  ms(hist.Max()),
+		},
+		LatencyCount: hist.Count(),
+	}
+}
@@ -0,0 +1,132 @@
+package bench
+
+import (
+	"math"
+	"math/bits"
+)
+
+// 每个二次幂区间划分为 halfCount 个子区间, 记录值的相对误差小于 1/halfCount
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits // 第一个区间 [0, 128) 精确记录
+	halfCount      = subBucketCount / 2
+	bucketCount    = (63 - subBucketBits + 2) * halfCount // 非负 int64 的最大子区间编号为 (63-7)*64+127
+)
+
+// Histogram 参考 HDR Histogram 的对数线性直方图, 用于统计延迟的分位数
+// 值按照二次幂划分区间, 每个区间再等分为 64 个子区间, 内存占用固定, 记录的相对误差小于 1/64
+// Histogram 不是并发安全的, 多个协程分别记录之后通过 Merge 合并
+type Histogram struct {
+	counts [bucketCount]int64
+	total  int64
+	min    int64
+	max    int64
+	sum    float64
+}
+
+// NewHistogram 创建直方图
+func NewHistogram() *Histogram {
+	return &Histogram{min: math.MaxInt64}
+}
+
+// Record 记录一个值, 负数按照 0 记录
+func (h *Histogram) Record(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	h.counts[bucketIndex(v)]++
+	h.total++
+	h.sum += float64(v)
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// Merge 合并另一个直方图的记录
+func (h *Histogram) Merge(o *Histogram) {
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.total += o.total
+	h.sum += o.sum
+	if o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+// Count 记录的值的数量
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+// Min 最小值, 没有记录时返回 0
+func (h *Histogram) Min() int64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.min
+}
+
+// Max 最大值
+func (h *Histogram) Max() int64 {
+	return h.max
+}
+
+// Mean 平均值
+func (h *Histogram) Mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / float64(h.total)
+}
+
+// Percentile 第 p 百分位的值(0-100), 返回所在子区间的上界, 不超过记录的最大值
+func (h *Histogram) Percentile(p float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p / 100 * float64(h.total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			v := bucketUpper(i)
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return v
+		}
+	}
+	return h.max
+}
+
+// bucketIndex 值所在的子区间, 区间 [2^s*64, 2^s*128) 的子区间宽度为 2^s
+func bucketIndex(v int64) int {
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	if shift <= 0 {
+		return int(v)
+	}
+	return shift*halfCount + int(v>>uint(shift))
+}
+
+// bucketUpper 子区间内的最大值
+func bucketUpper(i int) int64 {
+	if i < subBucketCount {
+		return int64(i)
+	}
+	shift := i/halfCount - 1
+	m := int64(i - shift*halfCount)
+	return m<<uint(shift) + (1 << uint(shift)) - 1
+}
@@ -0,0 +1,50 @@
+package bench
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	h := NewHistogram()
+	values := make([]int64, 0, 100000)
+	for i := 0; i < 100000; i++ {
+		v := rand.Int63n(int64(1e9))
+		values = append(values, v)
+		h.Record(v)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	for _, p := range []float64{50, 90, 99, 99.9, 100} {
+		want := values[int(p/100*float64(len(values)))-1]
+		got := h.Percentile(p)
+		// 对数线性的子区间保证相对误差小于 1/64
+		if diff := float64(got-want) / float64(want); diff < 0 || diff > 1.0/64 {
+			t.Errorf("Percentile(%v) = %d, want %d (diff %.4f)", p, got, want, diff)
+		}
+	}
+	if h.Count() != 100000 || h.Min() != values[0] || h.Max() != values[len(values)-1] {
+		t.Errorf("count=%d min=%d max=%d", h.Count(), h.Min(), h.Max())
+	}
+}
+
+func TestHistogramSmallValuesAndMerge(t *testing.T) {
+	a, b := NewHistogram(), NewHistogram()
+	for v := int64(0); v < 100; v++ {
+		a.Record(v)
+		b.Record(v + 100)
+	}
+	a.Merge(b)
+	if a.Count() != 200 || a.Min() != 0 || a.Max() != 199 {
+		t.Fatalf("count=%d min=%d max=%d", a.Count(), a.Min(), a.Max())
+	}
+	// 128 以内的值精确记录
+	if got := a.Percentile(50); got != 99 {
+		t.Errorf("Percentile(50) = %d, want 99", got)
+	}
+	for i := 0; i < bucketCount; i++ {
+		if bucketIndex(bucketUpper(i)) != i {
+			t.Fatalf("bucketIndex(bucketUpper(%d)) = %d", i, bucketIndex(bucketUpper(i)))
+		}
+	}
+}
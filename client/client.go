@@ -2,13 +2,19 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"github.com/AdeMQ/client/wincmd"
+	"os"
 )
 
 func main() {
 	// remote.address flag.String("address", "127.0.0.1:10601", "远程服务端地址")
 	flag.Parse()
 
-	cli := wincmd.NewWinClient()
-	cli.Run()
+	cli, err := wincmd.NewWinClient()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(cli.Start())
 }
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/AdeMQ/client/bench"
+	"github.com/AdeMQ/client/output"
+	"github.com/AdeMQ/client/remote"
+	"strings"
+	"time"
+)
+
+var CmdBench = &Command{
+	Name:    "bench",
+	Summary: "压测服务端的吞吐量与端到端延迟, 使用与当前连接相同的地址与认证建立独立的连接, 按下 Ctrl-C 提前结束",
+	Flags: []Flag{
+		{Name: "--producers", Value: "N", Usage: "生产者连接数, 默认为1", Kind: FlagInt},
+		{Name: "--consumers", Value: "M", Usage: "消费者连接数, 每个消费者都收到全部消息, 默认为1", Kind: FlagInt},
+		{Name: "--size", Value: "bytes", Usage: "消息大小, 默认为128, 最小为8", Kind: FlagInt},
+		{Name: "--rate", Value: "msgs", Usage: "所有生产者合计每秒发送的消息数, 默认不限速", Kind: FlagInt},
+		{Name: "--batch", Value: "N", Usage: "每个请求发送的消息数, 大于1时批量写入, 默认为1", Kind: FlagInt},
+		{Name: "--duration", Value: "duration", Usage: "发送的持续时间, 默认为10s", Kind: FlagDuration},
+		{Name: "--topic", Value: "topic", Usage: "压测使用的主题, 默认为 bench-<时间戳>"},
+	},
+	Handle: benchmark,
+}
+
+func benchmark(ctx context.Context, args *Args) interface{} {
+	srv, _ := remoteFrom(ctx)
+	cfg := bench.Config{
+		Topic:     args.String("--topic"),
+		Producers: args.Int("--producers", 1),
+		Consumers: args.Int("--consumers", 1),
+		Size:      args.Int("--size", 128),
+		Rate:      args.Int("--rate", 0),
+		Batch:     args.Int("--batch", 1),
+		Duration:  args.Duration("--duration", 10*time.Second),
+	}
+	dial := func(ctx context.Context, opts ...remote.Option) (*remote.Remote, error) {
+		return srv.Redial(ctx, opts...)
+	}
+	// 文本格式下每秒输出一次进度, 结构化的输出格式只输出最终结果
+	var progress func(time.Duration, int64, int64)
+	if p, ok := ctx.Value(ConstPrinter).(*output.Printer); ok && p.Format() == output.FormatText {
+		progress = func(elapsed time.Duration, sent, received int64) {
+			emit(ctx, fmt.Sprintf("%3.0fs sent=%d received=%d", elapsed.Seconds(), sent, received))
+		}
+	}
+	ret, err := bench.Run(ctx, cfg, dial, progress)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	l := ret.LatencyMs
+	lines := []string{
+		fmt.Sprintf("topic=%s producers=%d consumers=%d size=%d batch=%d elapsed=%.2fs",
+			ret.Topic, ret.Producers, ret.Consumers, ret.Size, ret.Batch, ret.Elapsed),
+		fmt.Sprintf("send:    %d msgs, %.0f msgs/sec, %.2f MB/sec, %d errors", ret.Sent, ret.SendMsgRate, ret.SendMBRate, ret.SendErrors),
+		fmt.Sprintf("receive: %d msgs, %.0f msgs/sec, %.2f MB/sec, %d lost", ret.Received, ret.RecvMsgRate, ret.RecvMBRate, ret.Lost),
+	}
+	if ret.LatencyCount > 0 {
+		lines = append(lines, fmt.Sprintf("latency: min=%.3fms mean=%.3fms p50=%.3fms p90=%.3fms p99=%.3fms p99.9=%.3fms max=%.3fms",
+			l.Min, l.Mean, l.P50, l.P90, l.P99, l.P999, l.Max))
+	}
+	return output.Result{Text: strings.Join(lines, "\n"), Data: ret}
+}
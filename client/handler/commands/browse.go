@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/AdeMQ/client/output"
+	"github.com/AdeMQ/protocol/message"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var CmdPeek = &Command{
+	Name:    "peek",
+	Summary: "查看队列头部即将投递的消息, 不会取出消息, 也不影响消息的投递与确认",
+	Args: []Arg{
+		{Name: "queue", Usage: "队列名称"},
+		{Name: "n", Usage: "查看的消息数, 默认为1", Optional: true},
+	},
+	Flags:  []Flag{{Name: "--hex", Usage: "以十六进制格式输出消息内容", Kind: FlagBool}},
+	Handle: peek,
+}
+
+var CmdBrowse = &Command{
+	Name:    "browse",
+	Summary: "分页查看主题保留的消息或者队列中的消息(包括等待确认的消息), 不会改变消息的投递状态",
+	Args: []Arg{
+		{Name: "topic|queue", Usage: "资源类型"},
+		{Name: "name", Usage: "主题或者队列名称"},
+	},
+	Flags: []Flag{
+		{Name: "--from", Value: "offset", Usage: "起始位置, 主题为偏移量, 默认从最早保留的消息开始; 队列为消息的位置, 默认为0"},
+		{Name: "--limit", Value: "N", Usage: "查看的消息数, 默认为20", Kind: FlagInt},
+		{Name: "--hex", Usage: "以十六进制格式输出消息内容", Kind: FlagBool},
+	},
+	Handle: browse,
+}
+
+func peek(ctx context.Context, args *Args) interface{} {
+	n := args.Arg(1)
+	if n == "" {
+		n = "1"
+	}
+	if v, err := strconv.Atoi(n); err != nil || v <= 0 {
+		return "Error: n 必须为正整数"
+	}
+	var msgs []message.Browsed
+	if errMsg := call(ctx, &message.Request{Cmd: "peek", Params: []string{args.Arg(0), n}}, &msgs); errMsg != "" {
+		return errMsg
+	}
+	if len(msgs) == 0 {
+		return "(empty queue)"
+	}
+	return formatBrowsed(msgs, args.Has("--hex"), "")
+}
+
+func browse(ctx context.Context, args *Args) interface{} {
+	resource, name := args.Arg(0), args.Arg(1)
+	if resource != "topic" && resource != "queue" {
+		return "Error: 资源类型需要为 topic 或者 queue"
+	}
+	from := args.String("--from")
+	if from == "" {
+		from = "0"
+	}
+	if n, err := strconv.ParseInt(from, 10, 64); err != nil || n < 0 {
+		return "Error: --from 必须为非负整数"
+	}
+	params := []string{resource, name, from, strconv.Itoa(args.Int("--limit", 20))}
+	var ret message.Browse
+	if errMsg := call(ctx, &message.Request{Cmd: "browse", Params: params}, &ret); errMsg != "" {
+		return errMsg
+	}
+	footer := fmt.Sprintf("-- %d messages, end --", len(ret.Messages))
+	if ret.More {
+		footer = fmt.Sprintf("-- %d messages, next page: browse %s %s --from %d --", len(ret.Messages), resource, name, ret.Next)
+	}
+	return formatBrowsed(ret.Messages, args.Has("--hex"), footer)
+}
+
+// call 发送请求并将响应的 Data 转换为 v, 失败时返回错误信息
+func call(ctx context.Context, req *message.Request, v interface{}) string {
+	srv, _ := remoteFrom(ctx)
+	resp, err := srv.Call(ctx, req)
+	if err != nil {
+		return errString(err)
+	}
+	if resp.Code != message.CodeOK {
+		return "Error: " + resp.Msg
+	}
+	if err = resp.DecodeData(v); err != nil {
+		return "Error: " + err.Error()
+	}
+	return ""
+}
+
+// formatBrowsed 每条消息先输出一行消息信息, 之后输出消息内容, 消息之间以空行分隔
+func formatBrowsed(msgs []message.Browsed, forceHex bool, footer string) output.Result {
+	t := output.NewTable("topic", "queue", "offset", "id", "state", "time", "key", "headers", "payload")
+	blocks := make([]string, 0, len(msgs)+1)
+	for _, m := range msgs {
+		ts := time.Unix(0, m.Timestamp*int64(time.Millisecond)).Format("2006-01-02 15:04:05.000")
+		head := fmt.Sprintf("%s@%d", m.Topic, m.Offset)
+		if m.Queue != "" {
+			head = fmt.Sprintf("%s#%d", m.Queue, m.ID)
+		}
+		head += " " + ts
+		if m.State != "" {
+			head += " " + m.State
+		}
+		if m.Deadline > 0 {
+			head += " deadline=" + time.Unix(0, m.Deadline*int64(time.Millisecond)).Format("15:04:05.000")
+		}
+		if m.Key != "" {
+			head += " key=" + m.Key
+		}
+		if h := headerString(m.Headers); h != "" {
+			head += " [" + h + "]"
+		}
+		blocks = append(blocks, head+"\n"+renderPayload(m.Payload, forceHex))
+		t.Append(m.Topic, m.Queue, m.Offset, m.ID, m.State, ts, m.Key, m.Headers, payloadString(m.Payload))
+	}
+	if footer != "" {
+		blocks = append(blocks, footer)
+	}
+	return output.Result{Text: strings.Join(blocks, "\n\n"), Data: t}
+}
+
+func headerString(headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+headers[k])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// renderPayload 消息内容的可读形式: JSON 格式化输出, 文本原样输出, 二进制内容以及指定 forceHex 时输出 hexdump
+func renderPayload(b []byte, forceHex bool) string {
+	if len(b) == 0 {
+		return "(empty)"
+	}
+	if forceHex || !isText(b) {
+		return strings.TrimSuffix(hex.Dump(b), "\n")
+	}
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		var buf bytes.Buffer
+		if json.Indent(&buf, trimmed, "", "  ") == nil {
+			return buf.String()
+		}
+	}
+	return string(b)
+}
+
+// isText 内容是否为合法 UTF-8 并且不包含换行与制表符以外的控制字符
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
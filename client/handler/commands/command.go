@@ -0,0 +1,251 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HandleFunc 命令处理函数, args 为按照命令定义校验之后的参数
+type HandleFunc func(ctx context.Context, args *Args) interface{}
+
+// FlagKind 选项值的类型, 解析参数时按照类型校验
+type FlagKind int
+
+const (
+	FlagString   FlagKind = iota // 任意字符串
+	FlagBool                     // 开关选项, 不带值
+	FlagInt                      // 正整数
+	FlagDuration                 // 时长, 例如 500ms, 1s
+)
+
+// Flag 命令的选项
+type Flag struct {
+	Name   string // 选项名称, 包含前缀, 例如 --count, -c
+	Value  string // 选项值在帮助信息中的名称, 例如 N
+	Usage  string
+	Kind   FlagKind
+	Repeat bool // 是否可以指定多次
+}
+
+// Arg 命令的位置参数
+type Arg struct {
+	Name     string
+	Usage    string
+	Optional bool
+	Variadic bool // 可以有多个值, 只能是最后一个参数
+}
+
+// Command 命令的定义, 帮助信息与参数校验都根据定义生成
+// 新增命令只需要定义 Command 并在 handler 的 initCommands 中注册
+type Command struct {
+	Name    string
+	Aliases []string
+	Summary string // 命令介绍
+	Args    []Arg
+	Flags   []Flag
+	Local   bool // 本地命令, 不需要连接到服务端
+	Handle  HandleFunc
+}
+
+// Args 校验之后的命令参数
+type Args struct {
+	Positional []string
+	flags      map[string][]string
+}
+
+// String 选项的值, 未指定时返回空, 指定多次时返回最后一次的值
+func (a *Args) String(name string) string {
+	values := a.flags[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Strings 可以指定多次的选项的所有值
+func (a *Args) Strings(name string) []string {
+	return a.flags[name]
+}
+
+// Has 是否指定了选项
+func (a *Args) Has(name string) bool {
+	_, ok := a.flags[name]
+	return ok
+}
+
+// Int 整数选项的值, 未指定时返回 def
+func (a *Args) Int(name string, def int) int {
+	if !a.Has(name) {
+		return def
+	}
+	n, _ := strconv.Atoi(a.String(name))
+	return n
+}
+
+// Duration 时长选项的值, 未指定时返回 def
+func (a *Args) Duration(name string, def time.Duration) time.Duration {
+	if !a.Has(name) {
+		return def
+	}
+	d, _ := time.ParseDuration(a.String(name))
+	return d
+}
+
+// Arg 第 i 个位置参数, 不存在时返回空
+func (a *Args) Arg(i int) string {
+	if i < len(a.Positional) {
+		return a.Positional[i]
+	}
+	return ""
+}
+
+// Parse 按照命令定义解析并校验参数
+// 以 - 开头并且之后为字母或者 - 的参数作为选项处理, 单独的 - 以及 -1 等参数作为位置参数, -- 之后的参数都作为位置参数
+func (c *Command) Parse(params []string) (*Args, error) {
+	args := &Args{flags: make(map[string][]string)}
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		if p == "--" {
+			args.Positional = append(args.Positional, params[i+1:]...)
+			break
+		}
+		if !isFlag(p) {
+			args.Positional = append(args.Positional, p)
+			continue
+		}
+		f := c.flag(p)
+		if f == nil {
+			return nil, fmt.Errorf("未知的选项 %s", p)
+		}
+		if _, ok := args.flags[f.Name]; ok && !f.Repeat {
+			return nil, fmt.Errorf("选项 %s 只能指定一次", f.Name)
+		}
+		if f.Kind == FlagBool {
+			args.flags[f.Name] = append(args.flags[f.Name], "true")
+			continue
+		}
+		if i+1 >= len(params) {
+			return nil, fmt.Errorf("选项 %s 缺少参数值", f.Name)
+		}
+		i++
+		if err := f.check(params[i]); err != nil {
+			return nil, err
+		}
+		args.flags[f.Name] = append(args.flags[f.Name], params[i])
+	}
+	min, max := 0, len(c.Args)
+	for _, a := range c.Args {
+		if !a.Optional {
+			min++
+		}
+		if a.Variadic {
+			max = -1
+		}
+	}
+	if n := len(args.Positional); n < min || (max >= 0 && n > max) {
+		return nil, fmt.Errorf("usage: %s", c.Usage())
+	}
+	return args, nil
+}
+
+func (c *Command) flag(name string) *Flag {
+	for i := range c.Flags {
+		if c.Flags[i].Name == name {
+			return &c.Flags[i]
+		}
+	}
+	return nil
+}
+
+// check 按照选项的类型校验选项值
+func (f *Flag) check(value string) error {
+	switch f.Kind {
+	case FlagInt:
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+			return fmt.Errorf("选项 %s 必须为正整数", f.Name)
+		}
+	case FlagDuration:
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("选项 %s 格式错误, 例如 500ms, 1s", f.Name)
+		}
+	}
+	return nil
+}
+
+// isFlag 参数是否为选项
+func isFlag(p string) bool {
+	if len(p) < 2 || p[0] != '-' {
+		return false
+	}
+	c := p[1]
+	return c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Usage 命令格式, 例如 subscribe <topic...> [--count N]
+func (c *Command) Usage() string {
+	parts := []string{c.Name}
+	for _, a := range c.Args {
+		parts = append(parts, a.usage())
+	}
+	for _, f := range c.Flags {
+		parts = append(parts, f.usage())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (a Arg) usage() string {
+	name := a.Name
+	if a.Variadic {
+		name += "..."
+	}
+	if a.Optional {
+		return "[" + name + "]"
+	}
+	return "<" + name + ">"
+}
+
+func (f Flag) usage() string {
+	s := f.Name
+	if f.Kind != FlagBool {
+		s += " " + f.Value
+	}
+	s = "[" + s + "]"
+	if f.Repeat {
+		s += "..."
+	}
+	return s
+}
+
+// Help 命令的帮助信息
+func (c *Command) Help() string {
+	lines := []string{
+		c.Name + ":",
+		"    命令介绍:    " + c.Summary,
+		"    命令格式:    " + c.Usage(),
+	}
+	if len(c.Aliases) > 0 {
+		lines = append(lines, "    命令别名:    "+strings.Join(c.Aliases, ", "))
+	}
+	var params []string
+	for _, a := range c.Args {
+		kind := "必选参数"
+		if a.Optional {
+			kind = "可选参数"
+		}
+		params = append(params, fmt.Sprintf("%s %s: %s", a.usage(), kind, a.Usage))
+	}
+	for _, f := range c.Flags {
+		params = append(params, fmt.Sprintf("%s 可选参数: %s", strings.TrimSuffix(f.usage(), "..."), f.Usage))
+	}
+	if len(params) == 0 {
+		params = []string{"无"}
+	}
+	lines = append(lines, "    命令参数:    "+params[0])
+	for _, p := range params[1:] {
+		lines = append(lines, "                "+p)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommandParse(t *testing.T) {
+	cmd := &Command{
+		Name: "publish",
+		Args: []Arg{{Name: "topic"}, {Name: "payload", Optional: true, Variadic: true}},
+		Flags: []Flag{
+			{Name: "--key", Value: "key"},
+			{Name: "--header", Value: "k=v", Repeat: true},
+			{Name: "--repeat", Value: "N", Kind: FlagInt},
+			{Name: "-q", Kind: FlagBool},
+		},
+	}
+	cases := []struct {
+		params     []string
+		positional []string
+		err        bool
+	}{
+		{[]string{"orders"}, []string{"orders"}, false},
+		{[]string{"orders", "a", "--key", "k", "b"}, []string{"orders", "a", "b"}, false},
+		{[]string{"orders", "-", "-1"}, []string{"orders", "-", "-1"}, false},
+		{[]string{"orders", "--", "--key"}, []string{"orders", "--key"}, false},
+		{[]string{"orders", "--header", "a=1", "--header", "b=2", "-q"}, []string{"orders"}, false},
+		{[]string{}, nil, true},
+		{[]string{"orders", "--key"}, nil, true},
+		{[]string{"orders", "--key", "a", "--key", "b"}, nil, true},
+		{[]string{"orders", "--repeat", "0"}, nil, true},
+		{[]string{"orders", "--unknown", "x"}, nil, true},
+	}
+	for _, c := range cases {
+		args, err := cmd.Parse(c.params)
+		if (err != nil) != c.err {
+			t.Errorf("Parse(%q) error = %v, want error %v", c.params, err, c.err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(args.Positional, c.positional) {
+			t.Errorf("Parse(%q) = %q, want %q", c.params, args.Positional, c.positional)
+		}
+	}
+
+	args, _ := cmd.Parse([]string{"orders", "--header", "a=1", "--header", "b=2", "--repeat", "3", "-q"})
+	if got := args.Strings("--header"); !reflect.DeepEqual(got, []string{"a=1", "b=2"}) {
+		t.Errorf("Strings(--header) = %q", got)
+	}
+	if args.Int("--repeat", 1) != 3 || args.Int("--missing", 1) != 1 || !args.Has("-q") {
+		t.Errorf("unexpected flag values %v", args.flags)
+	}
+	if want := "publish <topic> [payload...] [--key key] [--header k=v]... [--repeat N] [-q]"; cmd.Usage() != want {
+		t.Errorf("Usage() = %q, want %q", cmd.Usage(), want)
+	}
+}
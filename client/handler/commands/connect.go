@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+// Connector 管理命令行客户端与服务端之间的连接
+type Connector interface {
+	// Connect 连接到指定地址的服务端, 成功之后替换当前的连接, 失败时保留当前的连接
+	// user 为空时使用 -user 与 -password 参数指定的用户
+	Connect(addr, user, password string) error
+	// Disconnect 断开当前的连接, 未连接时返回错误
+	Disconnect() error
+}
+
+var CmdConnect = &Command{
+	Name:    "connect",
+	Summary: "连接到指定地址的服务端, 连接成功之后断开当前的连接",
+	Args: []Arg{
+		{Name: "host:port", Usage: "服务端地址, unix socket 使用 unix:/path/to/ademq.sock 的格式"},
+		{Name: "user", Usage: "认证的用户名, 默认使用 -user 参数", Optional: true},
+		{Name: "password", Usage: "认证的密码, 默认使用 -password 参数", Optional: true},
+	},
+	Local:  true,
+	Handle: connect,
+}
+
+var CmdDisconnect = &Command{
+	Name:    "disconnect",
+	Summary: "断开与服务端的连接, 之后只能执行 help 与 history 等本地命令",
+	Local:   true,
+	Handle:  disconnect,
+}
+
+func connect(ctx context.Context, args *Args) interface{} {
+	conn, ok := ctx.Value(ConstConnector).(Connector)
+	if !ok {
+		return "Error: connector error"
+	}
+	if len(args.Positional) == 2 {
+		return "Error: 指定用户名时需要同时指定密码"
+	}
+	addr := args.Arg(0)
+	if err := conn.Connect(addr, args.Arg(1), args.Arg(2)); err != nil {
+		return "Error: 服务器连接失败: " + err.Error()
+	}
+	return fmt.Sprintf("connected to %s", addr)
+}
+
+func disconnect(ctx context.Context, args *Args) interface{} {
+	conn, ok := ctx.Value(ConstConnector).(Connector)
+	if !ok {
+		return "Error: connector error"
+	}
+	if err := conn.Disconnect(); err != nil {
+		return "Error: " + err.Error()
+	}
+	return "disconnected"
+}
@@ -1,6 +1,10 @@
 package commands
 
-const ConstHelp = "help"
+// 注入到命令上下文中的键
+const ConstCommands = "commands"
+const ConstConnector = "connector"
 const ConstHistory = "history"
-const ConstPing = "ping"
+const ConstListener = "listener"
+const ConstPrinter = "printer"
 const ConstRemote = "remote"
+const ConstStdin = "stdin"
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"context"
+	"github.com/AdeMQ/client/output"
+	"strings"
+)
+
+var CmdFormat = &Command{
+	Name:    "format",
+	Summary: "查看或者修改命令结果的输出格式",
+	Args: []Arg{{
+		Name:     "text|json|table",
+		Usage:    "text 为对齐的文本, json 为每行一个 JSON 对象, table 为带边框的表格, 不指定时显示当前的输出格式",
+		Optional: true,
+	}},
+	Local:  true,
+	Handle: format,
+}
+
+func format(ctx context.Context, args *Args) interface{} {
+	printer, ok := ctx.Value(ConstPrinter).(*output.Printer)
+	if !ok {
+		return "Error: printer error"
+	}
+	if len(args.Positional) == 0 {
+		return output.Result{
+			Text: "当前输出格式: " + printer.Format() + ", 可选 " + strings.Join(output.Formats, "|"),
+			Data: output.NewTable("format").Append(printer.Format()),
+		}
+	}
+	if err := printer.SetFormat(args.Arg(0)); err != nil {
+		return "Error: " + err.Error()
+	}
+	return ""
+}
@@ -2,40 +2,46 @@ package commands
 
 import (
 	"context"
-	"fmt"
-	"sort"
+	"github.com/AdeMQ/client/output"
 	"strings"
 )
 
-func DescHelp() string {
-	return `
-help:
-    命令介绍:    获取帮助信息
-    命令格式:    help [cmd]
-    命令参数:    [cmd] 可选参数: 为你想要获取帮助信息的命令`
+var CmdHelp = &Command{
+	Name:    "help",
+	Summary: "获取帮助信息, 不指定命令时列出所有命令",
+	Args:    []Arg{{Name: "cmd", Usage: "为你想要获取帮助信息的命令", Optional: true}},
+	Local:   true,
+	Handle:  help,
 }
 
-func Help(ctx context.Context, params ...string) interface{} {
-	help := ctx.Value(ConstHelp)
-	helpInfo, ok := help.(map[string]string)
+func help(ctx context.Context, args *Args) interface{} {
+	cmds, ok := ctx.Value(ConstCommands).([]*Command)
 	if !ok {
-		return "Error: helpInfo error"
+		return "Error: commands error"
 	}
-	// 如果给定了第二个参数并符合某个命令，直接给单个命令的帮助信息
-	if len(params) >= 1 {
-		p1 := params[0]
-		if cmd, ok := helpInfo[p1]; ok {
-			return cmd
+	// 如果给定了参数并符合某个命令或者别名，直接给单个命令的帮助信息
+	if name := strings.ToLower(args.Arg(0)); name != "" {
+		for _, cmd := range cmds {
+			if cmd.Name == name || contains(cmd.Aliases, name) {
+				return cmd.Help()
+			}
 		}
+		return "Error: 命令不存在: " + name
 	}
-	var names []string
-	for name := range helpInfo {
-		names = append(names, name)
+	ret := output.NewTable("command", "usage", "summary")
+	var lines []string
+	for _, cmd := range cmds {
+		ret.Append(cmd.Name, cmd.Usage(), cmd.Summary)
+		lines = append(lines, cmd.Help())
 	}
-	sort.Strings(names)
-	ret := ""
-	for _, name := range names {
-		ret += fmt.Sprintf("%s", helpInfo[name])
+	return output.Result{Text: strings.Join(lines, "\n\n"), Data: ret}
+}
+
+func contains(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
 	}
-	return strings.TrimPrefix(ret, "\n")
+	return false
 }
@@ -2,27 +2,51 @@ package commands
 
 import (
 	"context"
-	"fmt"
-	"strings"
+	"github.com/AdeMQ/client/output"
+	"strconv"
 )
 
-func DescHistory() string {
-	return `
-history:
-    命令介绍:    获取历史命令记录
-    命令格式:    history
-    命令参数:    无`
+// HistoryStore 历史记录
+type HistoryStore interface {
+	All() []interface{}
+	Clear() error
 }
 
-func History(ctx context.Context, params ...string) interface{} {
-	history := ctx.Value(ConstHistory)
-	cmdHis, ok := history.([]interface{})
+var CmdHistory = &Command{
+	Name:    "history",
+	Summary: "获取历史命令记录, 可以通过 !n 重新执行第 n 条记录, !! 重新执行上一条命令",
+	Args:    []Arg{{Name: "N", Usage: "只显示最近的 N 条记录", Optional: true}},
+	Flags:   []Flag{{Name: "-c", Usage: "清空历史记录以及历史文件", Kind: FlagBool}},
+	Local:   true,
+	Handle:  history,
+}
+
+func history(ctx context.Context, args *Args) interface{} {
+	store, ok := ctx.Value(ConstHistory).(HistoryStore)
 	if !ok {
 		return "Error: history error"
 	}
-	ret := ""
-	for idx, val := range cmdHis {
-		ret += fmt.Sprintf("%d    %s\n", idx, val)
+	if args.Has("-c") {
+		if err := store.Clear(); err != nil {
+			return "Error: " + err.Error()
+		}
+		return ""
+	}
+	cmdHis := store.All()
+	start := 0
+	if len(args.Positional) > 0 {
+		n, err := strconv.Atoi(args.Arg(0))
+		if err != nil || n < 0 {
+			return "Error: N 必须为非负整数"
+		}
+		if n < len(cmdHis) {
+			start = len(cmdHis) - n
+		}
+	}
+	// 序号从1开始, 与 !n 对应
+	ret := output.NewTable("index", "command")
+	for idx := start; idx < len(cmdHis); idx++ {
+		ret.Append(idx+1, cmdHis[idx])
 	}
-	return strings.TrimSuffix(ret, "\n")
+	return ret
 }
@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/AdeMQ/client/output"
+	"github.com/AdeMQ/protocol/message"
+	"sort"
+	"strings"
+	"time"
+)
+
+var CmdInfo = &Command{
+	Name:    "info",
+	Summary: "查看服务端的版本、配置摘要、连接数、主题与队列的消息统计、内存、存储以及复制状态",
+	Args:    []Arg{{Name: "section", Usage: "只查看指定的分类: " + strings.Join(message.InfoSections, "|"), Optional: true}},
+	Handle:  info,
+}
+
+func info(ctx context.Context, args *Args) interface{} {
+	srv, _ := remoteFrom(ctx)
+	req := &message.Request{Cmd: "info", Params: args.Positional}
+	resp, err := srv.Call(ctx, req)
+	if err != nil {
+		return errString(err)
+	}
+	if resp.Code != message.CodeOK {
+		return "Error: " + resp.Msg
+	}
+	var ret message.Info
+	if err = resp.DecodeData(&ret); err != nil {
+		return "Error: " + err.Error()
+	}
+	// 分类的输出顺序与 message.InfoSections 一致, 每个分类以 # 开头, 分类之间以空行分隔
+	var sections []string
+	add := func(title string, lines ...string) {
+		sections = append(sections, "# "+title+"\n"+strings.Join(lines, "\n"))
+	}
+	want := func(name string) bool {
+		return len(args.Positional) == 0 || strings.EqualFold(args.Arg(0), name)
+	}
+	if s := ret.Server; s != nil {
+		add("Server", fields(
+			"version", s.Version,
+			"go_version", s.GoVersion,
+			"os", s.OS+"/"+s.Arch,
+			"pid", s.PID,
+			"started", time.Unix(0, s.StartTime*int64(time.Millisecond)).Format("2006-01-02 15:04:05"),
+			"uptime", time.Duration(s.Uptime)*time.Second,
+		)...)
+	}
+	if c := ret.Config; c != nil {
+		listeners := make([]string, 0, len(c.Listeners))
+		for _, l := range c.Listeners {
+			s := l.Network + " " + l.Address
+			if l.TLS {
+				s += " tls"
+			}
+			if l.MaxConns > 0 {
+				s += fmt.Sprintf(" maxConns=%d", l.MaxConns)
+			}
+			listeners = append(listeners, s)
+		}
+		rates := make([]string, 0, len(c.LimitRates))
+		for _, k := range sortedNames(c.LimitRates) {
+			rates = append(rates, fmt.Sprintf("%s=%d", k, c.LimitRates[k]))
+		}
+		add("Config", fields(
+			"listeners", strings.Join(listeners, ", "),
+			"buffer", fmt.Sprintf("%dk/%dk", c.BufLen, c.BufMaxLen),
+			"auth", fmt.Sprintf("%t users=%d acl_rules=%d", c.Auth, c.Users, c.ACLRules),
+			"limit", strings.Join(append([]string{c.LimitMode}, rates...), " "),
+			"compression", fmt.Sprintf("%s threshold=%d", orNone(strings.Join(c.Codecs, ",")), c.Threshold),
+			"retention", c.Retention,
+			"ack_timeout", time.Duration(c.AckTimeout)*time.Second,
+		)...)
+	}
+	if c := ret.Clients; c != nil {
+		add("Clients", fields(
+			"connected", c.Connected,
+			"total_connections", c.Total,
+			"subscriptions", c.Subscriptions,
+			"consumers", c.Consumers,
+		)...)
+	}
+	if want("topics") {
+		t := output.NewTable("name", "messages", "bytes", "offsets", "subscribers", "delayed", "publish/s", "deliver/s")
+		for _, topic := range ret.Topics {
+			// 保留的消息的偏移量范围, 没有保留的消息时为 -
+			offsets := "-"
+			if topic.Messages > 0 {
+				offsets = fmt.Sprintf("%d-%d", topic.FirstOffset, topic.NextOffset-1)
+			}
+			t.Append(topic.Name, topic.Messages, formatBytes(topic.Bytes), offsets,
+				topic.Subscribers, topic.Delayed, rate(topic.PublishRate), rate(topic.DeliverRate))
+		}
+		add("Topics", tableOrNone(t))
+	}
+	if want("queues") {
+		t := output.NewTable("name", "ready", "inflight", "bytes", "consumers", "pushed", "acked", "push/s", "deliver/s", "ack/s")
+		for _, q := range ret.Queues {
+			t.Append(q.Name, q.Ready, q.Inflight, formatBytes(q.Bytes), q.Consumers, q.Pushed, q.Acked,
+				rate(q.PushRate), rate(q.DeliverRate), rate(q.AckRate))
+		}
+		add("Queues", tableOrNone(t))
+	}
+	if m := ret.Memory; m != nil {
+		add("Memory", fields(
+			"heap_alloc", formatBytes(int64(m.HeapAlloc)),
+			"heap_inuse", formatBytes(int64(m.HeapInuse)),
+			"heap_idle", formatBytes(int64(m.HeapIdle)),
+			"sys", formatBytes(int64(m.Sys)),
+			"buffer_pool", formatBytes(m.BufferPool),
+			"num_gc", m.NumGC,
+			"goroutines", m.Goroutines,
+		)...)
+	}
+	if s := ret.Storage; s != nil {
+		add("Storage", fields(
+			"engine", s.Engine,
+			"persistent", s.Persistent,
+			"topics", s.Topics,
+			"queues", s.Queues,
+			"messages", s.Messages,
+			"bytes", formatBytes(s.Bytes),
+		)...)
+	}
+	if r := ret.Replication; r != nil {
+		add("Replication", fields("role", r.Role, "replicas", r.Replicas)...)
+	}
+	return output.Result{Text: strings.Join(sections, "\n\n"), Data: ret}
+}
+
+// fields 将 名称, 值 交替排列的参数格式化为名称对齐的多行文本
+func fields(kv ...interface{}) []string {
+	width := 0
+	for i := 0; i < len(kv); i += 2 {
+		if n := len(kv[i].(string)); n > width {
+			width = n
+		}
+	}
+	lines := make([]string, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		lines = append(lines, fmt.Sprintf("%-*s %v", width+1, kv[i].(string)+":", kv[i+1]))
+	}
+	return lines
+}
+
+func tableOrNone(t *output.Table) string {
+	if len(t.Rows) == 0 {
+		return "(none)"
+	}
+	return t.String()
+}
+
+func rate(r float64) string {
+	return fmt.Sprintf("%.1f", r)
+}
+
+// formatBytes 以 B/KB/MB/GB 为单位格式化字节数
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	v, suffix := float64(n)/unit, "KB"
+	for _, s := range []string{"MB", "GB", "TB"} {
+		if v < unit {
+			break
+		}
+		v, suffix = v/unit, s
+	}
+	return fmt.Sprintf("%.1f%s", v, suffix)
+}
+
+func sortedNames(m map[string]int) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
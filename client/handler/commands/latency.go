@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/AdeMQ/client/output"
+)
+
+var CmdLatency = &Command{
+	Name:    "latency",
+	Summary: "查看与远程服务器之间最近的往返延迟统计, 样本来自心跳以及 ping",
+	Handle:  latency,
+}
+
+func latency(ctx context.Context, args *Args) interface{} {
+	srv, _ := remoteFrom(ctx)
+	stats := srv.Latency()
+	if stats.Samples == 0 {
+		return "no samples yet"
+	}
+	return output.Result{
+		Text: fmt.Sprintf("samples=%d min=%s avg=%s p99=%s max=%s last=%s",
+			stats.Samples, formatRTT(stats.Min), formatRTT(stats.Avg), formatRTT(stats.P99), formatRTT(stats.Max), formatRTT(stats.Last)),
+		Data: output.NewTable("samples", "min_ms", "avg_ms", "p99_ms", "max_ms", "last_ms").
+			Append(stats.Samples, ms(stats.Min), ms(stats.Avg), ms(stats.P99), ms(stats.Max), ms(stats.Last)),
+	}
+}
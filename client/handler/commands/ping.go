@@ -2,35 +2,82 @@ package commands
 
 import (
 	"context"
-	"github.com/AdeMQ/client/remote"
+	"fmt"
+	"github.com/AdeMQ/client/output"
+	"strings"
+	"time"
 )
 
-func DescPing() string {
-	return `
-ping:
-    命令介绍:    向远程服务器发送连接消息
-    命令格式:    ping
-    命令参数:    无`
+var CmdPing = &Command{
+	Name:    "ping",
+	Summary: "向远程服务器发送连接消息, 并显示往返延迟",
+	Flags:   []Flag{{Name: "-c", Value: "count", Usage: "发送次数, 默认为1, 每次间隔1秒", Kind: FlagInt}},
+	Handle:  ping,
 }
 
-func Ping(ctx context.Context, params ...string) interface{} {
-	// TODO 远程命令考虑整体封装，统一处理
-	server := ctx.Value(ConstRemote)
-	srv, ok := server.(*remote.Remote)
-	if !ok {
-		return "Error: remote error"
+func ping(ctx context.Context, args *Args) interface{} {
+	srv, _ := remoteFrom(ctx)
+	count := args.Int("-c", 1)
+
+	var (
+		received int
+		min, max time.Duration
+		sum      time.Duration
+	)
+	sent := 0
+	for seq := 1; seq <= count; seq++ {
+		if seq > 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+		}
+		// 按下 Ctrl-C 时停止发送, 输出已经发送的统计信息
+		if ctx.Err() != nil && seq > 1 {
+			break
+		}
+		sent++
+		// 未设置截止时间, 使用 -timeout 指定的默认超时时间
+		rtt, err := srv.Ping(ctx)
+		if err != nil {
+			if count == 1 {
+				return errString(err)
+			}
+			emit(ctx, fmt.Sprintf("%s (ping %s: seq=%d)", errString(err), srv.Addr(), seq))
+			continue
+		}
+		received++
+		sum += rtt
+		if min == 0 || rtt < min {
+			min = rtt
+		}
+		if rtt > max {
+			max = rtt
+		}
+		// 单次 ping 直接返回结果, 多次 ping 逐条输出, 最后返回统计信息
+		reply := output.Result{
+			Text: fmt.Sprintf("pong from %s: seq=%d time=%s", srv.Addr(), seq, formatRTT(rtt)),
+			Data: output.NewTable("addr", "seq", "time_ms").Append(srv.Addr(), seq, ms(rtt)),
+		}
+		if count == 1 {
+			reply.Text = fmt.Sprintf("pong from %s: time=%s", srv.Addr(), formatRTT(rtt))
+			return reply
+		}
+		emit(ctx, reply)
 	}
-	data, err := remote.FormatRequest(ConstPing, params)
-	if err != nil {
-		return "Error: cmd format error"
+	ret := []string{
+		fmt.Sprintf("--- %s ping statistics ---", srv.Addr()),
+		fmt.Sprintf("%d sent, %d received, %.1f%% loss", sent, received, float64(sent-received)*100/float64(sent)),
 	}
-	err = srv.SendMsgToRequestChan(data)
-	if err != nil {
-		return err
+	if received == 0 {
+		// 全部失败时作为错误返回, 便于脚本判断服务是否可用
+		return "Error: " + strings.Join(ret, "\n")
 	}
-	result, err := srv.GetResponseFromChan()
-	if err != nil {
-		return err.Error()
+	avg := sum / time.Duration(received)
+	ret = append(ret, fmt.Sprintf("rtt min/avg/max = %s/%s/%s", formatRTT(min), formatRTT(avg), formatRTT(max)))
+	return output.Result{
+		Text: strings.Join(ret, "\n"),
+		Data: output.NewTable("addr", "sent", "received", "min_ms", "avg_ms", "max_ms").
+			Append(srv.Addr(), sent, received, ms(min), ms(avg), ms(max)),
 	}
-	return string(result)
 }
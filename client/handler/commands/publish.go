@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AdeMQ/client/output"
+	"github.com/AdeMQ/protocol/message"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+var CmdPublish = &Command{
+	Name:    "publish",
+	Aliases: []string{"pub"},
+	Summary: "向主题写入消息, 消息内容按照原始字节发送, 支持二进制内容",
+	Args: []Arg{
+		{Name: "topic", Usage: "主题名称"},
+		{Name: "payload", Usage: "消息内容, 多个参数以空格拼接; @file 从文件中读取, 例如 @order.json; - 从标准输入读取直到 EOF(交互模式下按 Ctrl-D 结束)", Optional: true, Variadic: true},
+	},
+	Flags: []Flag{
+		{Name: "--key", Value: "key", Usage: "消息键"},
+		{Name: "--header", Value: "k=v", Usage: "消息头, 可以指定多次", Repeat: true},
+		{Name: "--delay", Value: "duration", Usage: "延迟投递, 例如 30s, 服务端保存消息并在到期之后分配偏移量投递给订阅者, 最长 24h", Kind: FlagDuration},
+		{Name: "--interval", Value: "duration", Usage: "与 --repeat 一起使用时两次发送之间的间隔, 例如 500ms, 由客户端等待", Kind: FlagDuration},
+		{Name: "--repeat", Value: "N", Usage: "重复发送 N 次, 默认为1", Kind: FlagInt},
+	},
+	Handle: publish,
+}
+
+// readPayload 按照参数读取消息内容: 单独的 @file 从文件读取, 单独的 - 从标准输入读取, 否则以空格拼接参数
+func readPayload(args []string, stdin io.Reader) ([]byte, error) {
+	if len(args) == 1 && args[0] == "-" {
+		if stdin == nil {
+			return nil, errors.New("标准输入不可用, 从标准输入读取命令时请使用 @file 指定消息内容")
+		}
+		return ioutil.ReadAll(stdin)
+	}
+	if len(args) == 1 && strings.HasPrefix(args[0], "@") && len(args[0]) > 1 {
+		return ioutil.ReadFile(args[0][1:])
+	}
+	return []byte(strings.Join(args, " ")), nil
+}
+
+func publish(ctx context.Context, args *Args) interface{} {
+	srv, _ := remoteFrom(ctx)
+	topic := args.Arg(0)
+	var headers map[string]string
+	for _, h := range args.Strings("--header") {
+		kv := strings.SplitN(h, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return "Error: --header 的格式为 k=v"
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[kv[0]] = kv[1]
+	}
+	interval, repeat := args.Duration("--interval", 0), args.Int("--repeat", 1)
+	delay := args.Duration("--delay", 0)
+	if delay < 0 || (delay > 0 && delay < time.Millisecond) {
+		return "Error: --delay 最小为 1ms"
+	}
+	stdin, _ := ctx.Value(ConstStdin).(io.Reader)
+	payload, err := readPayload(args.Positional[1:], stdin)
+	if err != nil {
+		return "Error: 读取消息内容失败: " + err.Error()
+	}
+	ret := struct {
+		Offset    int64 `json:"offset"`
+		DeliverAt int64 `json:"deliverAt"` // 延迟消息预计的投递时间, 单位 毫秒
+	}{}
+	var first, last int64
+	sent := 0
+	for sent < repeat {
+		if interval > 0 && sent > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+			}
+		}
+		// 按下 Ctrl-C 时停止发送, 返回已经发送的结果
+		if ctx.Err() != nil {
+			break
+		}
+		req := &message.Request{
+			Cmd:     "publish",
+			Params:  []string{topic},
+			Payload: payload,
+			Key:     args.String("--key"),
+			Headers: headers,
+			Delay:   delay.Milliseconds(),
+		}
+		resp, err := srv.Call(ctx, req)
+		if err != nil {
+			return errString(err)
+		}
+		if resp.Code != message.CodeOK {
+			return "Error: " + resp.Msg
+		}
+		if err = resp.DecodeData(&ret); err != nil {
+			return "Error: " + err.Error()
+		}
+		if sent == 0 {
+			first = ret.Offset
+		}
+		last = ret.Offset
+		sent++
+	}
+	if sent == 0 {
+		return "Error: 请求已取消"
+	}
+	if delay > 0 {
+		// 延迟消息到期之后才分配偏移量
+		at := time.Unix(0, ret.DeliverAt*int64(time.Millisecond)).Format("2006-01-02 15:04:05.000")
+		return output.Result{
+			Text: fmt.Sprintf("scheduled %d messages to %s: deliver at %s size=%d", sent, topic, at, len(payload)),
+			Data: output.NewTable("topic", "count", "deliver_at", "size").Append(topic, sent, at, len(payload)),
+		}
+	}
+	text := fmt.Sprintf("published to %s: offset=%d size=%d", topic, last, len(payload))
+	if repeat > 1 {
+		text = fmt.Sprintf("published %d messages to %s: offsets=%d..%d size=%d", sent, topic, first, last, len(payload))
+	}
+	return output.Result{
+		Text: text,
+		Data: output.NewTable("topic", "count", "first_offset", "last_offset", "size").
+			Append(topic, sent, first, last, len(payload)),
+	}
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/AdeMQ/client/output"
+	"strings"
+)
+
+var CmdStatus = &Command{
+	Name:    "status",
+	Summary: "查看当前连接的服务端地址、连接状态、认证用户、压缩算法以及最近一次的往返延迟",
+	Local:   true,
+	Handle:  status,
+}
+
+func status(ctx context.Context, args *Args) interface{} {
+	srv, ok := remoteFrom(ctx)
+	if !ok {
+		return output.Result{
+			Text: "state: not connected",
+			Data: output.NewTable("addr", "state").Append("", "not connected"),
+		}
+	}
+	user, codec, last := srv.User(), srv.Codec(), ""
+	if stats := srv.Latency(); stats.Samples > 0 {
+		last = formatRTT(stats.Last)
+	}
+	ret := output.NewTable("addr", "state", "user", "tls", "compress", "latency").
+		Append(srv.Addr(), srv.State().String(), user, srv.TLS(), codec, last)
+	lines := []string{
+		"addr:     " + srv.Addr(),
+		"state:    " + srv.State().String(),
+		"user:     " + orNone(user),
+		fmt.Sprintf("tls:      %t", srv.TLS()),
+		"compress: " + orNone(codec),
+		"latency:  " + orNone(last),
+	}
+	return output.Result{Text: strings.Join(lines, "\n"), Data: ret}
+}
+
+// orNone 空值显示为 -
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/AdeMQ/client/output"
+	"github.com/AdeMQ/protocol/message"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// PushListener 接收服务端推送的消息
+type PushListener interface {
+	// Listen 开始接收推送的消息, 调用返回的 stop 之后不再接收, 同一时间只能有一个接收方
+	Listen() (msgs <-chan *message.Message, stop func())
+}
+
+var CmdSubscribe = &Command{
+	Name:    "subscribe",
+	Aliases: []string{"sub"},
+	Summary: "订阅主题并持续输出推送的消息(时间、主题、偏移量以及内容), 按下 Ctrl-C 结束并取消订阅",
+	Args:    []Arg{{Name: "topic", Usage: "一个或者多个主题名称", Variadic: true}},
+	Flags: []Flag{
+		{Name: "--count", Value: "N", Usage: "输出 N 条消息之后结束", Kind: FlagInt},
+		{Name: "--filter", Value: "pattern", Usage: "只输出内容匹配正则表达式的消息"},
+		{Name: "--from", Value: "offset", Usage: "先补发偏移量不小于 offset 的历史消息"},
+	},
+	Handle: subscribe,
+}
+
+func subscribe(ctx context.Context, args *Args) interface{} {
+	srv, _ := remoteFrom(ctx)
+	listener, ok := ctx.Value(ConstListener).(PushListener)
+	if !ok {
+		return "Error: listener error"
+	}
+	count := args.Int("--count", 0)
+	var filter *regexp.Regexp
+	if args.Has("--filter") {
+		re, err := regexp.Compile(args.String("--filter"))
+		if err != nil {
+			return "Error: --filter 格式错误: " + err.Error()
+		}
+		filter = re
+	}
+	from := args.String("--from")
+	if n, err := strconv.ParseInt(from, 10, 64); from != "" && (err != nil || n < 0) {
+		return "Error: --from 必须为非负整数"
+	}
+	// 先开始接收再订阅, 避免丢失订阅之后立即推送的消息
+	msgs, stop := listener.Listen()
+	topics := make(map[string]bool)
+	defer func() {
+		// 先停止接收再取消订阅, 否则取消订阅期间推送的消息无人接收, 会阻塞连接的读取协程, 取消订阅的响应也就无法读取
+		stop()
+		// ctx 可能已经被 Ctrl-C 取消, 取消订阅使用新的 ctx
+		for topic := range topics {
+			req := &message.Request{Cmd: "unsubscribe", Params: []string{topic}}
+			_, _ = srv.Call(context.Background(), req)
+		}
+	}()
+	for _, topic := range args.Positional {
+		req := &message.Request{Cmd: "subscribe", Params: []string{topic}}
+		if from != "" {
+			req.Params = append(req.Params, from)
+		}
+		resp, err := srv.Call(ctx, req)
+		if err != nil {
+			return errString(err)
+		}
+		if resp.Code != message.CodeOK {
+			return "Error: " + resp.Msg
+		}
+		topics[topic] = true
+	}
+
+	received := 0
+	for count == 0 || received < count {
+		select {
+		case msg := <-msgs:
+			if !topics[msg.Topic] || (filter != nil && !filter.Match(msg.Payload)) {
+				continue
+			}
+			received++
+			emit(ctx, formatMessage(msg))
+		case <-ctx.Done():
+			return fmt.Sprintf("received %d messages", received)
+		}
+	}
+	return fmt.Sprintf("received %d messages", received)
+}
+
+// formatMessage 格式化一条推送的消息, 带有消息键或者消息头时一起输出
+func formatMessage(msg *message.Message) output.Result {
+	ts := time.Unix(0, msg.Timestamp*int64(time.Millisecond)).Format("2006-01-02 15:04:05.000")
+	payload := payloadString(msg.Payload)
+	text := fmt.Sprintf("%s %s@%d", ts, msg.Topic, msg.Offset)
+	if msg.Key != "" {
+		text += " key=" + msg.Key
+	}
+	if headers := headerString(msg.Headers); headers != "" {
+		text += " [" + headers + "]"
+	}
+	return output.Result{
+		Text: text + " " + payload,
+		Data: output.NewTable("time", "topic", "offset", "key", "headers", "payload").
+			Append(ts, msg.Topic, msg.Offset, msg.Key, msg.Headers, payload),
+	}
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/AdeMQ/client/output"
+	"github.com/AdeMQ/client/remote"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// remoteFrom 获取当前的服务端连接, 未连接时返回 false
+// 分发器只会在已经连接时执行非本地命令, 这类命令可以忽略返回的 bool
+func remoteFrom(ctx context.Context) (*remote.Remote, bool) {
+	srv, ok := ctx.Value(ConstRemote).(*remote.Remote)
+	return srv, ok && srv != nil
+}
+
+// errString 格式化请求错误, 超时与取消单独提示, 便于与服务端返回的错误区分
+func errString(err error) string {
+	switch err {
+	case remote.ErrTimeout:
+		return "Error: 请求超时, 可以通过 -timeout 参数调整超时时间"
+	case context.Canceled:
+		return "Error: 请求已取消"
+	}
+	return "Error: " + err.Error()
+}
+
+// formatRTT 以毫秒为单位格式化往返延迟
+func formatRTT(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d)/float64(time.Millisecond))
+}
+
+// ms 以毫秒为单位的浮点数, 用于结构化输出
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// payloadString 消息内容的可读形式, 不是合法 UTF-8 的二进制内容以带转义的引号字符串显示
+func payloadString(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return strconv.Quote(string(b))
+}
+
+// emit 命令执行过程中按照当前的输出格式立即输出一条结果, 用于持续输出的命令
+func emit(ctx context.Context, v interface{}) {
+	if p, ok := ctx.Value(ConstPrinter).(*output.Printer); ok {
+		p.Print(v)
+	}
+}
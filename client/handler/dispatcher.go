@@ -3,51 +3,70 @@ package handler
 import (
 	"context"
 	"github.com/AdeMQ/client/handler/commands"
+	"github.com/AdeMQ/client/output"
 	"github.com/AdeMQ/client/remote"
+	"io"
 )
 
-type HandleFunc func(context context.Context, params ...string) interface{}
-
 type Dispatcher struct {
-	HelpInfo map[string]string
+	Commands []*commands.Command          // 注册的命令, 按照名称排序
+	Lookup   map[string]*commands.Command // 命令名称以及别名 => 命令
 	History  *CmdHistory
-	Handlers map[string]HandleFunc
+	Printer  *output.Printer // 命令结果的输出器, 持续输出的命令通过它在执行过程中输出结果
+	// Connector 管理与服务端之间的连接, 由命令行客户端设置, 用于 connect 与 disconnect 命令
+	Connector commands.Connector
+	// Listener 接收服务端推送的消息, 由命令行客户端设置, 用于 subscribe 命令
+	Listener commands.PushListener
+	// Stdin 标准输入, 用于 publish - 从标准输入读取消息内容
+	Stdin io.Reader
 }
 
-func NewDispatcher() *Dispatcher {
+func NewDispatcher(printer *output.Printer, history *CmdHistory) *Dispatcher {
+	cmds := initCommands()
+	lookup := make(map[string]*commands.Command)
+	for _, cmd := range cmds {
+		lookup[cmd.Name] = cmd
+		for _, alias := range cmd.Aliases {
+			lookup[alias] = cmd
+		}
+	}
 	return &Dispatcher{
-		HelpInfo: initHelpInfo(),
-		History:  NewCmdHistory(),
-		Handlers: initHandlers(),
+		Commands: cmds,
+		Lookup:   lookup,
+		History:  history,
+		Printer:  printer,
 	}
 }
 
-func (d *Dispatcher) Dispatch(cmd *ParsedCmd, remote *remote.Remote) interface{} {
+// Dispatch 执行命令, ctx 被取消(例如按下 Ctrl-C)时持续执行的命令结束
+func (d *Dispatcher) Dispatch(ctx context.Context, cmd *ParsedCmd, remote *remote.Remote) interface{} {
 	// 拦截空命令
 	if cmd.Cmd == "" {
 		return ""
 	}
-	// 历史记录计入到数据结构中
-	d.History.Push(cmd.Cmd)
+	// 历史记录计入到数据结构中, 保存完整的命令用于上下方向键以及 Ctrl-R 调出
+	d.History.Push(cmd.Origin)
 
-	// 正式执行函数
-	fn, ok := d.Handlers[cmd.Cmd]
+	c, ok := d.Lookup[cmd.Cmd]
 	if !ok {
-		return "命令不存在"
+		return "Error: 命令不存在: " + cmd.Cmd
 	}
-
-	ctx := context.WithValue(context.Background(), "remote", remote)
-	ctx = d.injectSpecialContext(ctx, cmd.Cmd)
-	return fn(ctx, cmd.Params...)
-}
-
-// 注入特殊命令需要的特殊上下文信息
-func (d *Dispatcher) injectSpecialContext(ctx context.Context, cmd string) context.Context {
-	switch cmd {
-	case commands.ConstHelp:
-		ctx = context.WithValue(ctx, commands.ConstHelp, d.HelpInfo)
-	case commands.ConstHistory:
-		ctx = context.WithValue(ctx, commands.ConstHistory, d.History.All())
+	// 按照命令定义校验参数, 未连接时只能执行本地命令
+	args, err := c.Parse(cmd.Params)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	if !c.Local && remote == nil {
+		return "Error: 未连接到服务器, 请先使用 connect <host:port> 连接"
 	}
-	return ctx
+
+	// 正式执行函数
+	ctx = context.WithValue(ctx, commands.ConstRemote, remote)
+	ctx = context.WithValue(ctx, commands.ConstPrinter, d.Printer)
+	ctx = context.WithValue(ctx, commands.ConstCommands, d.Commands)
+	ctx = context.WithValue(ctx, commands.ConstHistory, d.History)
+	ctx = context.WithValue(ctx, commands.ConstConnector, d.Connector)
+	ctx = context.WithValue(ctx, commands.ConstListener, d.Listener)
+	ctx = context.WithValue(ctx, commands.ConstStdin, d.Stdin)
+	return c.Handle(ctx, args)
 }
@@ -1,23 +1,70 @@
 package handler
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
 	"github.com/AdeMQ/datastruct/linear"
+	"os"
+	"strconv"
+	"strings"
 )
 
+// ConstHistorySize 默认保留的历史记录条数
+const ConstHistorySize = 1000
+
 // CmdHistory 命令历史记录
 // 我们使用了环形队列作为底层结构来实现历史记录的存存储
+// 指定了历史文件时, 每条命令都会追加写入文件, 下次启动时从文件中恢复
 type CmdHistory struct {
 	CmdList *linear.RingQueue
+	file    string // 历史文件路径, 为空表示不持久化
+	size    int
+	lines   int // 历史文件中的行数, 超过 size 的两倍时压缩文件
 }
 
-// NewCmdHistory 返回一个命令历史结构的指针
-func NewCmdHistory() *CmdHistory {
-	return &CmdHistory{
-		CmdList: linear.NewRingQueue(100),
+// NewCmdHistory 返回一个命令历史结构的指针, 保留最近的 size 条记录
+// file 不为空时从文件中加载历史记录, 文件不存在时在第一次写入时创建
+func NewCmdHistory(size int, file string) *CmdHistory {
+	if size <= 0 {
+		size = ConstHistorySize
+	}
+	c := &CmdHistory{
+		// 环形队列少用一个元素空间判定队列满
+		CmdList: linear.NewRingQueue(size + 1),
+		file:    file,
+		size:    size,
+	}
+	if file != "" {
+		c.load()
 	}
+	return c
 }
 
+// Push 追加一条历史记录, 包含密码等敏感信息的命令不会被记录
 func (c *CmdHistory) Push(cmd string) {
+	if cmd == "" || IsSecret(cmd) {
+		return
+	}
+	c.push(cmd)
+	if c.file == "" {
+		return
+	}
+	f, err := os.OpenFile(c.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintln(f, escapeHistory(cmd))
+	_ = f.Close()
+	if err != nil {
+		return
+	}
+	if c.lines++; c.lines >= c.size*2 {
+		c.save()
+	}
+}
+
+func (c *CmdHistory) push(cmd string) {
 	if c.CmdList.IsFull() {
 		_, _ = c.CmdList.DeQueue()
 	}
@@ -27,3 +74,127 @@ func (c *CmdHistory) Push(cmd string) {
 func (c *CmdHistory) All() []interface{} {
 	return c.CmdList.FetchAllElem()
 }
+
+// Clear 清空历史记录以及历史文件
+func (c *CmdHistory) Clear() error {
+	c.CmdList = linear.NewRingQueue(c.size + 1)
+	c.lines = 0
+	if c.file == "" {
+		return nil
+	}
+	if err := os.Remove(c.file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Expand 展开 !! (上一条命令) 以及 !n (第 n 条历史记录, 从1开始) 形式的命令, 其他命令原样返回
+func (c *CmdHistory) Expand(line string) (string, error) {
+	if !strings.HasPrefix(line, "!") {
+		return line, nil
+	}
+	all := c.All()
+	if line == "!!" {
+		if len(all) == 0 {
+			return "", errors.New("没有历史记录")
+		}
+		return all[len(all)-1].(string), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n <= 0 || n > len(all) {
+		return "", fmt.Errorf("历史记录 %s 不存在", line)
+	}
+	return all[n-1].(string), nil
+}
+
+// load 从历史文件中加载最近的记录
+func (c *CmdHistory) load() {
+	lines, total, err := readHistory(c.file, c.size)
+	if err != nil {
+		return
+	}
+	for _, line := range lines {
+		c.push(unescapeHistory(line))
+	}
+	c.lines = total
+}
+
+// save 压缩历史文件, 只保留最近的 size 条记录
+// 多个客户端可能共用同一个历史文件, 压缩时以文件的当前内容为准而不是本会话内存中的记录, 保留其他会话追加的记录
+func (c *CmdHistory) save() {
+	lines, _, err := readHistory(c.file, c.size)
+	if err != nil {
+		return
+	}
+	tmp := c.file + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		_, _ = fmt.Fprintln(w, line)
+	}
+	if err = w.Flush(); err == nil {
+		err = f.Close()
+	} else {
+		_ = f.Close()
+	}
+	if err == nil && os.Rename(tmp, c.file) == nil {
+		c.lines = len(lines)
+	}
+}
+
+// readHistory 读取历史文件中最近的 n 行记录, 返回的记录仍然是转义之后的形式, total 为文件中的记录总数
+func readHistory(file string, n int) (lines []string, total int, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			if len(lines) == n {
+				lines = lines[1:]
+			}
+			lines = append(lines, line)
+			total++
+		}
+	}
+	return lines, total, scanner.Err()
+}
+
+// IsSecret 命令是否包含密码等敏感信息, 这类命令不会被记录到历史中
+// 包括 auth 命令, 带有用户名与密码的 connect 命令, 以及包含 password 的命令
+func IsSecret(cmd string) bool {
+	fields := strings.Fields(strings.ToLower(cmd))
+	if len(fields) == 0 {
+		return false
+	}
+	if fields[0] == "connect" && len(fields) > 2 {
+		return true
+	}
+	return fields[0] == "auth" || strings.Contains(strings.ToLower(cmd), "password")
+}
+
+// escapeHistory 历史文件中每条记录占一行, 多行命令中的换行与反斜杠需要转义
+func escapeHistory(cmd string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(cmd)
+}
+
+func unescapeHistory(line string) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			i++
+			if line[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(line[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestHistoryCompactKeepsOtherSessions 两个会话共用历史文件, 压缩时不能丢弃另一个会话追加的记录
+func TestHistoryCompactKeepsOtherSessions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "history")
+
+	a := NewCmdHistory(3, file)
+	b := NewCmdHistory(3, file)
+	for i := 0; i < 5; i++ {
+		a.Push("a" + strconv.Itoa(i))
+	}
+	b.Push("b0")
+	// a 的第6条记录触发压缩
+	a.Push("a5")
+
+	lines, total, err := readHistory(file, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a4", "b0", "a5"}
+	if total != len(want) {
+		t.Fatalf("压缩之后的记录为 %v, want %v", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("压缩之后的记录为 %v, want %v", lines, want)
+		}
+	}
+}
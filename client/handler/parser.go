@@ -1,6 +1,14 @@
 package handler
 
-import "strings"
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnterminatedQuote   = errors.New("引号未闭合")
+	ErrUnterminatedHeredoc = errors.New("heredoc 缺少结束标记")
+)
 
 // ParsedCmd 解析之后的命令结构
 type ParsedCmd struct {
@@ -10,6 +18,11 @@ type ParsedCmd struct {
 }
 
 // Parser 命令解析器
+// 按照类似 shell 的规则切分参数:
+//   - 单引号内的内容原样保留
+//   - 双引号内以及引号之外的反斜杠转义下一个字符, 其中 \n \t 表示换行与制表符
+//   - 单引号之外行尾的反斜杠表示命令在下一行继续
+//   - 最后一个参数为 <<TAG 时, 之后直到单独一行 TAG 为止的内容作为一个参数(heredoc), 用于多行的消息内容
 type Parser struct{}
 
 // NewParser 创建命令解析器
@@ -17,27 +30,170 @@ func NewParser() *Parser {
 	return &Parser{}
 }
 
-// Parse 解析命令并返回结果
-func (p *Parser) Parse(cmdStr string) *ParsedCmd {
+// Parse 解析命令并返回结果, 引号未闭合或者 heredoc 缺少结束标记时返回错误
+func (p *Parser) Parse(cmdStr string) (*ParsedCmd, error) {
 	parsedCmd := &ParsedCmd{
 		Cmd:    "",
 		Params: make([]string, 0),
 		Origin: cmdStr,
 	}
-	tmpSlice := strings.Split(cmdStr, " ")
-	cmdStored := false
-	for _, str := range tmpSlice {
-		if str == "" || str == " " {
-			continue
+	line, body := cmdStr, ""
+	if i := strings.IndexByte(cmdStr, '\n'); i >= 0 && heredocTag(cmdStr[:i]) != "" {
+		line, body = cmdStr[:i], cmdStr[i+1:]
+	}
+	tokens, err := tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(tokens); n > 0 && strings.HasPrefix(tokens[n-1], "<<") && len(tokens[n-1]) > 2 {
+		content, ok := heredocBody(body, tokens[n-1][2:])
+		if !ok {
+			return nil, ErrUnterminatedHeredoc
 		}
-		// 如果命令字段未解析，先解析命令，忽略大小写
-		if !cmdStored {
-			parsedCmd.Cmd = strings.ToLower(str)
-			cmdStored = true
+		tokens[n-1] = content
+	}
+	for i, token := range tokens {
+		// 第一个字段为命令, 忽略大小写, 之后的字段为参数
+		if i == 0 {
+			parsedCmd.Cmd = strings.ToLower(token)
 			continue
 		}
-		// 如果是参数, 注入到参数中去
-		parsedCmd.Params = append(parsedCmd.Params, str)
+		parsedCmd.Params = append(parsedCmd.Params, token)
+	}
+	return parsedCmd, nil
+}
+
+// Incomplete 判断输入是否需要继续读取下一行: 行尾为反斜杠, 或者 heredoc 还没有读取到结束标记
+func (p *Parser) Incomplete(input string) bool {
+	lines := strings.Split(input, "\n")
+	if tag := heredocTag(lines[0]); tag != "" {
+		for _, l := range lines[1:] {
+			if l == tag {
+				return false
+			}
+		}
+		return true
+	}
+	return endsWithEscape(input)
+}
+
+// endsWithEscape 按照 tokenize 的规则判断输入是否以起转义作用的反斜杠结尾, 单引号内的反斜杠原样保留, 不表示续行
+func endsWithEscape(s string) bool {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			if i+1 == len(s) {
+				return true
+			}
+			i++
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+	return false
+}
+
+// heredocTag 获取命令第一行中 heredoc 的结束标记, 没有时返回空
+func heredocTag(line string) string {
+	tokens, err := tokenize(line)
+	if err != nil || len(tokens) == 0 {
+		return ""
+	}
+	last := tokens[len(tokens)-1]
+	if !strings.HasPrefix(last, "<<") || len(last) <= 2 {
+		return ""
+	}
+	return last[2:]
+}
+
+// heredocBody 获取 heredoc 的内容, 不包含结束标记以及最后一个换行
+func heredocBody(body, tag string) (string, bool) {
+	lines := strings.Split(body, "\n")
+	for i, l := range lines {
+		if l == tag {
+			return strings.Join(lines[:i], "\n"), true
+		}
+	}
+	return "", false
+}
+
+// tokenize 按照空白字符切分参数, 处理引号、转义以及续行
+func tokenize(s string) ([]string, error) {
+	var (
+		tokens  []string
+		cur     strings.Builder
+		inToken bool // 当前是否有正在拼接的参数, 用于区分空参数 "" 与参数之间的空白
+		quote   byte // 当前所在的引号, 0 表示不在引号内
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				cur.WriteByte(c)
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else if c == '\\' && i+1 < len(s) {
+				i++
+				if s[i] != '\n' {
+					cur.WriteByte(unescape(s[i]))
+				}
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inToken = true
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				// 反斜杠加换行为续行, 两行直接拼接, 引号内同样处理
+				if s[i] != '\n' {
+					cur.WriteByte(unescape(s[i]))
+					inToken = true
+				}
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inToken {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+				inToken = false
+			}
+		default:
+			cur.WriteByte(c)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return nil, ErrUnterminatedQuote
+	}
+	if inToken {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
+
+// unescape 转义字符对应的实际字符, 其余字符原样返回
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
 	}
-	return parsedCmd
+	return c
 }
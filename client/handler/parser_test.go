@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	p := NewParser()
+	cases := []struct {
+		input  string
+		cmd    string
+		params []string
+		err    error
+	}{
+		{"PING", "ping", []string{}, nil},
+		{"  publish   orders  a b ", "publish", []string{"orders", "a", "b"}, nil},
+		{`publish orders '{"id": 1, "name": "a b"}'`, "publish", []string{"orders", `{"id": 1, "name": "a b"}`}, nil},
+		{`publish orders "say \"hi\"\n" x`, "publish", []string{"orders", "say \"hi\"\n", "x"}, nil},
+		{`publish orders a\ b '' ""`, "publish", []string{"orders", "a b", "", ""}, nil},
+		{`publish orders 'it'"'"'s'`, "publish", []string{"orders", "it's"}, nil},
+		{`publish orders 'a\' b`, "publish", []string{"orders", `a\`, "b"}, nil},
+		{"publish orders \\\n  payload", "publish", []string{"orders", "payload"}, nil},
+		{"publish orders <<EOF\n{\n  \"id\": 1\n}\nEOF", "publish", []string{"orders", "{\n  \"id\": 1\n}"}, nil},
+		{"publish orders <<EOF\n\nEOF", "publish", []string{"orders", ""}, nil},
+		{`publish orders "unterminated`, "", nil, ErrUnterminatedQuote},
+		{`publish orders 'unterminated`, "", nil, ErrUnterminatedQuote},
+		{"publish orders <<EOF\nbody", "", nil, ErrUnterminatedHeredoc},
+	}
+	for _, c := range cases {
+		got, err := p.Parse(c.input)
+		if err != c.err {
+			t.Errorf("Parse(%q) error = %v, want %v", c.input, err, c.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got.Cmd != c.cmd || !reflect.DeepEqual(got.Params, c.params) {
+			t.Errorf("Parse(%q) = %q %q, want %q %q", c.input, got.Cmd, got.Params, c.cmd, c.params)
+		}
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	p := NewParser()
+	cases := []struct {
+		input string
+		want  bool
+	}{
+		{"ping", false},
+		{"publish orders \\", true},
+		{"publish orders a\\\\", false},
+		{"publish orders <<EOF", true},
+		{"publish orders <<EOF\nline", true},
+		{"publish orders <<EOF\nline\nEOF", false},
+		{`publish orders "a b"`, false},
+		{`publish orders 'a\'`, false},
+		{`publish orders 'a\`, false},
+		{`publish orders "a\`, true},
+		{`publish orders 'a\' \`, true},
+	}
+	for _, c := range cases {
+		if got := p.Incomplete(c.input); got != c.want {
+			t.Errorf("Incomplete(%q) = %v, want %v", c.input, got, c.want)
+		}
+	}
+}
@@ -4,21 +4,22 @@ import (
 	"github.com/AdeMQ/client/handler/commands"
 )
 
-func initHelpInfo() map[string]string {
-	// TODO 新命令都需要注入进来
-	// 注入帮助信息（请按照字典顺序处理）
-	cmdHelp := make(map[string]string)
-	cmdHelp[commands.ConstHelp] = commands.DescHelp()
-	cmdHelp[commands.ConstHistory] = commands.DescHistory()
-	cmdHelp[commands.ConstPing] = commands.DescPing()
-	return cmdHelp
-}
-
-func initHandlers() map[string]HandleFunc {
-	// 所有新增的数据结构要通过此处注入进来（请按照字典顺序处理）
-	cmdDict := make(map[string]HandleFunc)
-	cmdDict[commands.ConstHelp] = commands.Help
-	cmdDict[commands.ConstHistory] = commands.History
-	cmdDict[commands.ConstPing] = commands.Ping
-	return cmdDict
+func initCommands() []*commands.Command {
+	// 所有新增的命令要通过此处注册, 帮助信息与参数校验根据命令定义生成（请按照字典顺序处理）
+	return []*commands.Command{
+		commands.CmdBench,
+		commands.CmdBrowse,
+		commands.CmdConnect,
+		commands.CmdDisconnect,
+		commands.CmdFormat,
+		commands.CmdHelp,
+		commands.CmdHistory,
+		commands.CmdInfo,
+		commands.CmdLatency,
+		commands.CmdPeek,
+		commands.CmdPing,
+		commands.CmdPublish,
+		commands.CmdStatus,
+		commands.CmdSubscribe,
+	}
 }
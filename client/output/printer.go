@@ -0,0 +1,173 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 输出格式
+const (
+	FormatText  = "text"  // 面向用户的文本, 表格按列对齐
+	FormatJSON  = "json"  // 每条结果(表格的每一行)输出为一行 JSON, 便于 jq 等工具处理
+	FormatTable = "table" // 带边框的表格
+)
+
+// Formats 支持的输出格式
+var Formats = []string{FormatText, FormatJSON, FormatTable}
+
+// Result 同时带有文本与结构化数据的命令结果
+// text 格式输出 Text, 其他格式输出 Data, Data 为 *Table 时按照表格处理
+type Result struct {
+	Text string
+	Data interface{}
+}
+
+// Printer 按照输出格式输出命令结果, 可以被多个协程并发使用
+type Printer struct {
+	mu     sync.Mutex
+	format string
+	Out    io.Writer // 成功的结果
+	Err    io.Writer // 失败的结果
+}
+
+// NewPrinter 创建输出器, format 不合法时返回错误
+func NewPrinter(format string, out, errOut io.Writer) (*Printer, error) {
+	p := &Printer{Out: out, Err: errOut}
+	if err := p.SetFormat(format); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Format 当前的输出格式
+func (p *Printer) Format() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.format
+}
+
+// SetFormat 修改输出格式
+func (p *Printer) SetFormat(format string) error {
+	format = strings.ToLower(format)
+	for _, f := range Formats {
+		if f == format {
+			p.mu.Lock()
+			p.format = format
+			p.mu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("不支持的输出格式 %s, 可选 %s", format, strings.Join(Formats, "|"))
+}
+
+// Print 输出一条命令结果, 结果表示失败时输出到 Err 并返回 false
+// 约定失败的结果为 error 或者以 Error 开头的字符串
+func (p *Printer) Print(v interface{}) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if IsError(v) {
+		msg := strings.TrimPrefix(strings.TrimPrefix(fmt.Sprint(v), "Error:"), "Error")
+		if p.format == FormatJSON {
+			p.writeJSON(p.Err, map[string]string{"error": strings.TrimSpace(msg)})
+		} else {
+			_, _ = fmt.Fprintln(p.Err, v)
+		}
+		return false
+	}
+	if v == nil || v == "" {
+		return true
+	}
+	switch p.format {
+	case FormatJSON:
+		p.printJSON(v)
+	case FormatTable:
+		p.printTable(v)
+	default:
+		p.printText(v)
+	}
+	return true
+}
+
+func (p *Printer) printText(v interface{}) {
+	switch val := v.(type) {
+	case Result:
+		_, _ = fmt.Fprintln(p.Out, val.Text)
+	case *Table:
+		_, _ = fmt.Fprintln(p.Out, val.render(false))
+	default:
+		_, _ = fmt.Fprintln(p.Out, v)
+	}
+}
+
+func (p *Printer) printTable(v interface{}) {
+	if r, ok := v.(Result); ok {
+		v = r.Data
+	}
+	switch val := v.(type) {
+	case *Table:
+		_, _ = fmt.Fprintln(p.Out, val.render(true))
+	case string:
+		_, _ = fmt.Fprintln(p.Out, val)
+	default:
+		// 其他结构按照字段输出为两列的表格
+		var fields map[string]interface{}
+		b, err := json.Marshal(val)
+		if err != nil || json.Unmarshal(b, &fields) != nil {
+			_, _ = fmt.Fprintln(p.Out, val)
+			return
+		}
+		t := NewTable("field", "value")
+		for _, k := range sortedKeys(fields) {
+			t.Append(k, fields[k])
+		}
+		_, _ = fmt.Fprintln(p.Out, t.render(true))
+	}
+}
+
+func (p *Printer) printJSON(v interface{}) {
+	if r, ok := v.(Result); ok {
+		v = r.Data
+	}
+	switch val := v.(type) {
+	case *Table:
+		for _, record := range val.Records() {
+			p.writeJSON(p.Out, record)
+		}
+	case string:
+		p.writeJSON(p.Out, map[string]string{"result": val})
+	default:
+		p.writeJSON(p.Out, val)
+	}
+}
+
+func (p *Printer) writeJSON(w io.Writer, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	_, _ = fmt.Fprintln(w, string(b))
+}
+
+// IsError 命令结果是否表示执行失败
+func IsError(v interface{}) bool {
+	switch val := v.(type) {
+	case error:
+		return true
+	case string:
+		return strings.HasPrefix(val, "Error")
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
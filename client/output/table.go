@@ -0,0 +1,111 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Table 表格形式的命令结果, text 与 table 格式输出为对齐的表格, json 格式每一行输出为一个 JSON 对象
+type Table struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// NewTable 创建表格
+func NewTable(columns ...string) *Table {
+	return &Table{Columns: columns}
+}
+
+// Append 追加一行, 值的数量需要与列数一致
+func (t *Table) Append(values ...interface{}) *Table {
+	t.Rows = append(t.Rows, values)
+	return t
+}
+
+// Records 将每一行转换为 列名 => 值 的结构, 用于 JSON 输出
+func (t *Table) Records() []map[string]interface{} {
+	records := make([]map[string]interface{}, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		record := make(map[string]interface{}, len(t.Columns))
+		for i, col := range t.Columns {
+			if i < len(row) {
+				record[col] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// String 渲染为不带边框的对齐表格, 用于拼接到文本结果中
+func (t *Table) String() string {
+	return t.render(false)
+}
+
+// render 渲染为对齐的表格, border 为 true 时输出边框
+func (t *Table) render(border bool) string {
+	cells := make([][]string, 0, len(t.Rows)+1)
+	cells = append(cells, t.Columns)
+	for _, row := range t.Rows {
+		line := make([]string, len(t.Columns))
+		for i := range line {
+			if i < len(row) {
+				line[i] = cellString(row[i])
+			}
+		}
+		cells = append(cells, line)
+	}
+	widths := make([]int, len(t.Columns))
+	for _, line := range cells {
+		for i, cell := range line {
+			if w := StringWidth(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	var b strings.Builder
+	sep := ""
+	if border {
+		parts := make([]string, len(widths))
+		for i, w := range widths {
+			parts[i] = strings.Repeat("-", w+2)
+		}
+		sep = "+" + strings.Join(parts, "+") + "+\n"
+		b.WriteString(sep)
+	}
+	for n, line := range cells {
+		parts := make([]string, len(line))
+		for i, cell := range line {
+			parts[i] = cell + strings.Repeat(" ", widths[i]-StringWidth(cell))
+		}
+		if border {
+			b.WriteString("| " + strings.Join(parts, " | ") + " |\n")
+		} else {
+			b.WriteString(strings.TrimRight(strings.Join(parts, "  "), " ") + "\n")
+		}
+		if n == 0 && border {
+			b.WriteString(sep)
+		}
+	}
+	if border && len(cells) > 1 {
+		b.WriteString(sep)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// cellString 单元格内容, 换行替换为 \n 避免破坏表格
+func cellString(v interface{}) string {
+	var s string
+	switch val := v.(type) {
+	case nil:
+		s = ""
+	case string:
+		s = val
+	case []byte:
+		s = string(val)
+	default:
+		s = fmt.Sprint(val)
+	}
+	return strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(s)
+}
@@ -0,0 +1,25 @@
+package output
+
+import "testing"
+
+func TestStringWidth(t *testing.T) {
+	cases := []struct {
+		s    string
+		want int
+	}{
+		{"abc", 3},
+		{"中文", 4},
+		{"ｱｲ", 2},      // 半角片假名
+		{"ＡＢ", 4},      // 全角字母
+		{"e\u0301", 1}, // e 加组合重音符
+		{"€→✓", 3},     // 三字节但不是宽字符
+		{"한글", 4},      // 韩文音节
+		{"🚀x", 3},      // emoji
+		{"→中", 3},
+	}
+	for _, c := range cases {
+		if got := StringWidth(c.s); got != c.want {
+			t.Errorf("StringWidth(%q) = %d, want %d", c.s, got, c.want)
+		}
+	}
+}
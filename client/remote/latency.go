@@ -0,0 +1,136 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"github.com/AdeMQ/datastruct/linear"
+	"github.com/AdeMQ/protocol/message"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyWindowSize 往返延迟滚动窗口保留的样本数
+const latencyWindowSize = 100
+
+// LatencyStats 往返延迟统计
+type LatencyStats struct {
+	Samples int
+	Min     time.Duration
+	Avg     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+	Last    time.Duration
+}
+
+// latencyWindow 保留最近的往返延迟样本, 底层使用环形队列, 满了之后丢弃最早的样本
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples *linear.RingQueue
+	last    time.Duration
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	// 环形队列少用一个元素空间判定队列满
+	return &latencyWindow{samples: linear.NewRingQueue(size + 1)}
+}
+
+func (w *latencyWindow) add(rtt time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.samples.IsFull() {
+		_, _ = w.samples.DeQueue()
+	}
+	w.samples.EnQueue(rtt)
+	w.last = rtt
+}
+
+func (w *latencyWindow) stats() LatencyStats {
+	w.mu.Lock()
+	all := w.samples.FetchAllElem()
+	last := w.last
+	w.mu.Unlock()
+
+	stats := LatencyStats{Samples: len(all), Last: last}
+	if len(all) == 0 {
+		return stats
+	}
+	sorted := make([]time.Duration, len(all))
+	var sum time.Duration
+	for i, e := range all {
+		sorted[i] = e.(time.Duration)
+		sum += sorted[i]
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	stats.Avg = sum / time.Duration(len(sorted))
+	// 最近邻秩方法计算 p99
+	stats.P99 = sorted[(len(sorted)*99+99)/100-1]
+	return stats
+}
+
+// Latency 获取最近的往返延迟统计, 样本来自心跳以及 Ping
+func (r *Remote) Latency() LatencyStats {
+	return r.latency.stats()
+}
+
+// Ping 向服务端发送 ping 并返回往返延迟
+func (r *Remote) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	resp, err := r.Call(ctx, &message.Request{Cmd: "ping"})
+	if err != nil {
+		return 0, err
+	}
+	if resp.Code != message.CodeOK {
+		return 0, errors.New(resp.Msg)
+	}
+	rtt := time.Since(start)
+	r.latency.add(rtt)
+	return rtt, nil
+}
+
+// HandleHeartBeat 按照间隔发送携带时间戳的心跳, 根据服务端返回的时间戳计算往返延迟
+// gen 为开启心跳时的连接代数, 连接断开或者关闭之后退出, 重连成功之后会为新的连接开启新的心跳协程
+func (r *Remote) HandleHeartBeat(gen uint64) {
+	if r.opts.HeartbeatInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.opts.HeartbeatInterval)
+	defer ticker.Stop()
+	for atomic.LoadUint64(&r.connGen) == gen {
+		if err := r.heartbeat(); err == ErrClosed || err == ErrDisconnected {
+			return
+		} else if err != nil && atomic.LoadUint64(&r.connGen) == gen {
+			log.Println("heart beat send err ", err.Error())
+		}
+		select {
+		case <-r.closeChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Remote) heartbeat() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.HeartbeatInterval)
+	defer cancel()
+	req := &message.Request{Cmd: "heartbeat", Params: []string{strconv.FormatInt(time.Now().UnixNano(), 10)}}
+	resp, err := r.Call(ctx, req)
+	if err != nil {
+		return err
+	}
+	var sent string
+	if err = resp.DecodeData(&sent); err != nil {
+		return err
+	}
+	ts, err := strconv.ParseInt(sent, 10, 64)
+	if err != nil {
+		return err
+	}
+	r.latency.add(time.Since(time.Unix(0, ts)))
+	return nil
+}
@@ -0,0 +1,125 @@
+package remote
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"time"
+)
+
+// Options 远程连接的配置
+type Options struct {
+	DialTimeout    time.Duration          // 建立连接以及握手的超时时间
+	RequestTimeout time.Duration          // 请求默认的超时时间, ctx 已经设置截止时间时以 ctx 为准, 0 表示不超时
+	User           string                 // 认证用户名, 为空表示不认证
+	Password       string                 // 认证密码
+	Codecs         []string               // 支持的压缩算法, 按照偏好顺序排列, 为空表示不压缩
+	TLS            bool                   // 是否使用 TLS 连接
+	TLSSkipVerify  bool                   // TLS 连接时跳过服务端证书校验
+	OnPush         func(*message.Message) // 服务端推送消息的回调, 在读取协程中执行, 不能阻塞
+	OnStateChange  func(State, error)     // 连接状态变化的回调, 断开时 error 为断开的原因
+	Reconnect      bool                   // 连接断开之后是否自动重连
+	// ReconnectMin 与 ReconnectMax 为重连退避时间的初始值与上限, 每次失败之后退避时间翻倍
+	ReconnectMin      time.Duration
+	ReconnectMax      time.Duration
+	ReconnectAttempts int           // 最大连续重连次数, 0 表示不限制
+	HeartbeatInterval time.Duration // 心跳间隔, 心跳同时用于测量往返延迟, 0 表示不发送心跳
+}
+
+// Option 远程连接的配置项
+type Option func(*Options)
+
+func defaultOptions() *Options {
+	return &Options{
+		DialTimeout:    5 * time.Second,
+		RequestTimeout: 5 * time.Second,
+		Reconnect:      true,
+		ReconnectMin:   defaultReconnectMin,
+		ReconnectMax:   30 * time.Second,
+		// 心跳间隔
+		HeartbeatInterval: 5 * time.Second,
+	}
+}
+
+// WithDialTimeout 设置建立连接以及握手的超时时间
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.DialTimeout = timeout
+	}
+}
+
+// WithRequestTimeout 设置请求默认的超时时间, 0 表示不超时
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.RequestTimeout = timeout
+	}
+}
+
+// WithAuth 设置认证的用户名与密码
+func WithAuth(user, password string) Option {
+	return func(o *Options) {
+		o.User = user
+		o.Password = password
+	}
+}
+
+// WithCompression 设置支持的压缩算法, 按照偏好顺序排列
+func WithCompression(codecs ...string) Option {
+	return func(o *Options) {
+		o.Codecs = codecs
+	}
+}
+
+// WithTLS 使用 TLS 连接, skipVerify 为 true 时跳过服务端证书校验, 仅用于测试
+func WithTLS(skipVerify bool) Option {
+	return func(o *Options) {
+		o.TLS = true
+		o.TLSSkipVerify = skipVerify
+	}
+}
+
+// WithPushHandler 设置服务端推送消息的回调
+func WithPushHandler(fn func(*message.Message)) Option {
+	return func(o *Options) {
+		o.OnPush = fn
+	}
+}
+
+// WithStateHandler 设置连接状态变化的回调
+func WithStateHandler(fn func(State, error)) Option {
+	return func(o *Options) {
+		o.OnStateChange = fn
+	}
+}
+
+// defaultReconnectMin 重连退避时间初始值的默认值
+const defaultReconnectMin = 100 * time.Millisecond
+
+// WithReconnect 设置重连的退避时间范围以及最大连续重连次数, attempts 为 0 表示不限制
+// min 不大于0时使用默认值, 避免不等待地连续重连, max 小于 min 时使用 min
+func WithReconnect(min, max time.Duration, attempts int) Option {
+	if min <= 0 {
+		min = defaultReconnectMin
+	}
+	if max < min {
+		max = min
+	}
+	return func(o *Options) {
+		o.Reconnect = true
+		o.ReconnectMin = min
+		o.ReconnectMax = max
+		o.ReconnectAttempts = attempts
+	}
+}
+
+// WithoutReconnect 连接断开之后不再重连
+func WithoutReconnect() Option {
+	return func(o *Options) {
+		o.Reconnect = false
+	}
+}
+
+// WithHeartbeat 设置心跳间隔, 0 表示不发送心跳
+func WithHeartbeat(interval time.Duration) Option {
+	return func(o *Options) {
+		o.HeartbeatInterval = interval
+	}
+}
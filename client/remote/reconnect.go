@@ -0,0 +1,180 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// State 连接状态
+type State int32
+
+const (
+	StateConnected    State = iota // 已连接
+	StateDisconnected              // 连接断开
+	StateReconnecting              // 正在重连
+	StateClosed                    // 已关闭, 不会再重连
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+var ErrDisconnected = errors.New("remote connection lost, reconnecting")
+
+// subState 已订阅的主题, 重连之后从最后收到的偏移量之后继续订阅
+type subState struct {
+	params     []string // 订阅时的参数
+	lastOffset int64    // 最后收到的消息偏移量, -1 表示还没有收到消息
+}
+
+// State 获取当前的连接状态
+func (r *Remote) State() State {
+	return State(atomic.LoadInt32(&r.state))
+}
+
+// setState 修改连接状态并通知回调
+func (r *Remote) setState(state State, err error) {
+	atomic.StoreInt32(&r.state, int32(state))
+	if r.opts.OnStateChange != nil {
+		r.opts.OnStateChange(state, err)
+	}
+}
+
+// disconnect 连接断开之后关闭旧连接, 等待中的请求立即返回 ErrDisconnected
+func (r *Remote) disconnect(err error) {
+	atomic.AddUint64(&r.connGen, 1)
+	r.setState(StateDisconnected, err)
+	r.closeConn()
+	r.pendingMu.Lock()
+	for id, ch := range r.pending {
+		close(ch)
+		delete(r.pending, id)
+	}
+	r.pendingMu.Unlock()
+}
+
+// reconnect 按照抖动的指数退避策略重新连接, 成功之后恢复会话状态
+// 连接被关闭或者达到最大重试次数时返回 false
+func (r *Remote) reconnect() bool {
+	backoff := r.opts.ReconnectMin
+	for attempt := 1; r.opts.ReconnectAttempts <= 0 || attempt <= r.opts.ReconnectAttempts; attempt++ {
+		r.setState(StateReconnecting, nil)
+		// 在 [backoff/2, backoff) 之间随机等待, 避免大量客户端同时重连
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-r.closeChan:
+			return false
+		case <-time.After(wait):
+		}
+		err := r.resume()
+		if err == nil {
+			r.setState(StateConnected, nil)
+			return true
+		}
+		log.Println("reconnect failed", attempt, err.Error())
+		if backoff *= 2; backoff > r.opts.ReconnectMax {
+			backoff = r.opts.ReconnectMax
+		}
+	}
+	return false
+}
+
+// resume 建立新连接, 完成握手并重新订阅之前订阅的主题
+func (r *Remote) resume() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.DialTimeout)
+	defer cancel()
+	conn, err := dial(ctx, r.addr, r.opts)
+	if err != nil {
+		return err
+	}
+	r.writeMu.Lock()
+	r.Conn = conn
+	r.codec = nil
+	r.writeMu.Unlock()
+	r.readStart, r.readEnd = 0, 0
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err = r.handshake(); err == nil {
+		err = r.resubscribe()
+	}
+	if err != nil {
+		r.closeConn()
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return nil
+}
+
+// resubscribe 重新订阅主题以及推送消费的队列, 已经收到过消息的主题从最后的偏移量之后继续
+func (r *Remote) resubscribe() error {
+	r.subsMu.Lock()
+	params := make([][]string, 0, len(r.subs))
+	for topic, sub := range r.subs {
+		p := sub.params
+		if sub.lastOffset >= 0 {
+			p = []string{topic, strconv.FormatInt(sub.lastOffset+1, 10)}
+		}
+		params = append(params, p)
+	}
+	consumes := make([][]string, 0, len(r.consumes))
+	for _, p := range r.consumes {
+		consumes = append(consumes, p)
+	}
+	r.subsMu.Unlock()
+	for _, p := range params {
+		if _, err := r.requestDirect("subscribe", p); err != nil {
+			return err
+		}
+	}
+	for _, p := range consumes {
+		if _, err := r.requestDirect("consume", p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackSession 记录需要在重连之后恢复的会话状态
+func (r *Remote) trackSession(cmd string, params []string) {
+	if len(params) == 0 {
+		return
+	}
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	switch cmd {
+	case "subscribe":
+		r.subs[params[0]] = &subState{params: params, lastOffset: -1}
+	case "unsubscribe":
+		delete(r.subs, params[0])
+	case "consume":
+		r.consumes[params[0]] = params
+	case "unconsume":
+		delete(r.consumes, params[0])
+	}
+}
+
+// trackOffset 记录订阅主题最后收到的消息偏移量
+func (r *Remote) trackOffset(topic string, offset int64) {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	if sub, ok := r.subs[topic]; ok && offset > sub.lastOffset {
+		sub.lastOffset = offset
+	}
+}
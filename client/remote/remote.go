@@ -1,12 +1,19 @@
 package remote
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"flag"
-	"io"
+	"github.com/AdeMQ/protocol/message"
+	"github.com/AdeMQ/protocol/packet"
 	"log"
 	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,47 +23,351 @@ const (
 )
 
 type Remote struct {
-	closed             bool        // 链接是否关闭
-	Conn               net.Conn    // rP连接
-	maxReadBufLen      int         // 最大接受缓冲区长度
-	readBuf            []byte      // 缓冲区
-	readStart          int         // 缓冲区数据开始位置
-	readEnd            int         // 缓冲区数据结束位置
-	RequestChan        chan []byte // 远程请求发送通道
-	RequestChanClosed  bool        // 远程请求队列是否关闭
-	ResponseChan       chan []byte // 远程结果通道（ 命令处理函数发送之后阻塞接收 ）
-	ResponseChanClosed bool        // 远程结果队列是否关闭
-}
-
-var address = flag.String("address", "127.0.0.1:10601", "远程服务端地址")
-
-func NewRemote() *Remote {
-	// TODO 远程连接的地址，需要通过，启动命令的时候给
-	conn, err := net.DialTimeout("tcp", *address, 5*time.Second)
+	closed        bool         // 链接是否关闭
+	Conn          net.Conn     // rP连接
+	maxReadBufLen int          // 最大接受缓冲区长度
+	readBuf       []byte       // 缓冲区
+	readStart     int          // 缓冲区数据开始位置
+	readEnd       int          // 缓冲区数据结束位置
+	codec         packet.Codec // 与服务端协商的压缩算法, 为 nil 表示不压缩
+	codecMinLen   int          // 消息体超过该长度才压缩
+	opts          *Options
+	writeMu       sync.Mutex                        // 保证同一条消息的头与体连续写入, 同时保护 Conn 与 codec
+	nextID        uint64                            // 请求编号
+	pendingMu     sync.Mutex                        // 保护 pending 与 closed
+	pending       map[uint64]chan *message.Response // 等待响应的请求
+	closeOnce     sync.Once
+	closeChan     chan struct{} // 关闭时 close, 用于中断重连的等待
+	addr          string        // 服务端地址, 用于重连
+	state         int32         // 连接状态 State
+	connGen       uint64        // 连接的代数, 每次断开时加一, 旧连接的心跳协程据此退出
+	subsMu        sync.Mutex
+	subs          map[string]*subState // 已订阅的主题, 重连之后恢复
+	consumes      map[string][]string  // 以推送方式消费的队列 => consume 命令的参数, 重连之后恢复
+	latency       *latencyWindow       // 往返延迟样本
+}
+
+var (
+	ErrClosed  = errors.New("remote connection closed")
+	ErrTimeout = errors.New("request timed out")
+)
+
+var (
+	address  = flag.String("address", "127.0.0.1:10601", "远程服务端地址, unix socket 使用 unix:/path/to/ademq.sock 的格式, 为空表示启动时不连接")
+	user     = flag.String("user", "", "认证用户名")
+	password = flag.String("password", "", "认证密码")
+	useTLS   = flag.Bool("tls", false, "是否使用 TLS 连接")
+	insecure = flag.Bool("tlsSkipVerify", false, "TLS 连接时跳过服务端证书校验, 仅用于测试")
+	compress = flag.String("compress", "flate,gzip", "支持的压缩算法, 按照偏好顺序以逗号分隔, 为空表示不压缩")
+	timeout  = flag.Duration("timeout", 5*time.Second, "请求默认的超时时间, 例如 500ms, 10s, 0 表示不超时")
+)
+
+// NewRemote 按照命令行参数连接到远程服务, opts 用于追加命令行参数之外的配置
+func NewRemote(opts ...Option) (*Remote, error) {
+	return NewRemoteTo(*address, opts...)
+}
+
+// NewRemoteTo 按照命令行参数连接到指定地址的远程服务, opts 优先于命令行参数
+func NewRemoteTo(addr string, opts ...Option) (*Remote, error) {
+	flagOpts := []Option{WithAuth(*user, *password), WithRequestTimeout(*timeout)}
+	if *compress != "" {
+		flagOpts = append(flagOpts, WithCompression(strings.Split(*compress, ",")...))
+	}
+	if *useTLS {
+		flagOpts = append(flagOpts, WithTLS(*insecure))
+	}
+	return Dial(context.Background(), addr, append(flagOpts, opts...)...)
+}
+
+// DefaultAddr 命令行参数指定的服务端地址, 为空表示启动时不连接
+func DefaultAddr() string {
+	return *address
+}
+
+// Dial 连接到远程服务, 完成压缩协商以及认证握手之后开启收发协程
+// 地址以 unix: 开头时使用 unix socket, 例如 unix:/var/run/ademq.sock
+func Dial(ctx context.Context, addr string, opts ...Option) (*Remote, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.DialTimeout)
+		defer cancel()
+	}
+	conn, err := dial(ctx, addr, o)
 	if err != nil {
-		panic("服务器连接失败")
+		return nil, err
+	}
+	r := &Remote{
+		closed:        false,
+		Conn:          conn,
+		maxReadBufLen: 1024 * 1024 * 10,
+		readBuf:       make([]byte, 1024*16),
+		readStart:     0,
+		readEnd:       0,
+		opts:          o,
+		pending:       make(map[uint64]chan *message.Response),
+		closeChan:     make(chan struct{}),
+		addr:          addr,
+		subs:          make(map[string]*subState),
+		consumes:      make(map[string][]string),
+		latency:       newLatencyWindow(latencyWindowSize),
 	}
-	return &Remote{
-		closed:             false,
-		Conn:               conn,
-		maxReadBufLen:      1024 * 1024 * 10,
-		readBuf:            make([]byte, 1024*16),
-		readStart:          0,
-		readEnd:            0,
-		RequestChan:        make(chan []byte),
-		RequestChanClosed:  false,
-		ResponseChan:       make(chan []byte),
-		ResponseChanClosed: false,
+	// 在开启收发协程之前同步完成压缩协商以及认证握手
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
 	}
+	if err = r.handshake(); err != nil {
+		r.closeConn()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	r.setState(StateConnected, nil)
+	r.start()
+	return r, nil
 }
 
-func (r *Remote) Init() {
-	go r.HandleConnWrite()
+// Redial 使用相同的地址、认证以及压缩配置建立一个新的连接, opts 用于覆盖部分配置
+// 新连接不会继承推送与连接状态的回调
+func (r *Remote) Redial(ctx context.Context, opts ...Option) (*Remote, error) {
+	o := *r.opts
+	o.OnPush, o.OnStateChange = nil, nil
+	base := func(dst *Options) {
+		*dst = o
+	}
+	return Dial(ctx, r.addr, append([]Option{base}, opts...)...)
+}
+
+// dial 建立到服务端的连接
+func dial(ctx context.Context, addr string, o *Options) (net.Conn, error) {
+	network := "tcp"
+	if strings.HasPrefix(addr, "unix:") {
+		network = "unix"
+		addr = strings.TrimPrefix(addr, "unix:")
+	}
+	if !o.TLS {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+	conf := &tls.Config{InsecureSkipVerify: o.TLSSkipVerify}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		conf.ServerName = host
+	}
+	d := &tls.Dialer{Config: conf}
+	return d.DialContext(ctx, network, addr)
+}
+
+// handshake 连接建立之后的压缩协商以及认证
+func (r *Remote) handshake() error {
+	if len(r.opts.Codecs) > 0 {
+		if err := r.Hello(r.opts.Codecs); err != nil {
+			return errors.New("服务器协商失败: " + err.Error())
+		}
+	}
+	if r.opts.User != "" {
+		if err := r.Auth(r.opts.User, r.opts.Password); err != nil {
+			return errors.New("服务器认证失败: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// Hello 向服务端发送支持的压缩算法并同步等待协商结果, 需要在开启收发协程之前调用
+func (r *Remote) Hello(codecs []string) error {
+	resp, err := r.requestDirect("hello", codecs)
+	if err != nil {
+		return err
+	}
+	ret := message.Hello{}
+	if err = resp.DecodeData(&ret); err != nil {
+		return err
+	}
+	if ret.Codec != "" {
+		r.writeMu.Lock()
+		r.codec = packet.CodecByName(ret.Codec)
+		r.codecMinLen = ret.Threshold
+		r.writeMu.Unlock()
+	}
+	return nil
+}
+
+// Auth 向服务端发送认证请求并同步等待结果, 需要在开启收发协程之前调用
+func (r *Remote) Auth(user, password string) error {
+	_, err := r.requestDirect("auth", []string{user, password})
+	return err
+}
+
+// requestDirect 同步发送请求并读取响应, 仅用于读取协程之外的握手阶段(首次连接以及重连)
+func (r *Remote) requestDirect(cmd string, params []string) (*message.Response, error) {
+	data, err := FormatRequest(cmd, params)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.sendMsgDirect(data); err != nil {
+		return nil, err
+	}
+	var resp *message.Response
+	for {
+		content, err := r.readMsgDirect()
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = message.DecodeResponse(content); err != nil {
+			return nil, err
+		}
+		// 重新订阅时补发的历史消息可能先于响应到达
+		if resp.Type != message.TypePush {
+			break
+		}
+		r.route(content)
+	}
+	if resp.Code != message.CodeOK {
+		return nil, errors.New(resp.Msg)
+	}
+	return resp, nil
+}
+
+// readMsgDirect 从连接中同步读取一条完整消息
+func (r *Remote) readMsgDirect() ([]byte, error) {
+	for {
+		if headBuf, err := r.Seek(ConstHeadSize); err == nil {
+			contentSize := r.BytesToInt(headBuf)
+			if r.ReadBufLen() >= contentSize+ConstHeadSize {
+				return packet.Decompress(packet.HeadCodecID(headBuf), r.Read(ConstHeadSize, contentSize), r.maxReadBufLen)
+			}
+		}
+		if _, err := r.ReadFromConn(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// start 开启读取与心跳协程, 请求由调用方直接写入连接
+func (r *Remote) start() {
 	go r.HandleConnRead()
-	go r.HandleHeartBeat()
+	go r.HandleHeartBeat(atomic.LoadUint64(&r.connGen))
 }
 
+// Call 发送请求并等待对应编号的响应, ctx 结束时放弃等待
+// ctx 没有设置截止时间时使用默认的请求超时时间, 超时返回 ErrTimeout, 被取消时返回 context.Canceled
+// 服务端返回的错误码不会转换为 error, 由调用方根据 Code 判断
+func (r *Remote) Call(ctx context.Context, req *message.Request) (*message.Response, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	req.ID = atomic.AddUint64(&r.nextID, 1)
+	ch := make(chan *message.Response, 1)
+	r.pendingMu.Lock()
+	if r.closed {
+		r.pendingMu.Unlock()
+		return nil, ErrClosed
+	}
+	if r.State() != StateConnected {
+		r.pendingMu.Unlock()
+		return nil, ErrDisconnected
+	}
+	r.pending[req.ID] = ch
+	r.pendingMu.Unlock()
+	defer func() {
+		r.pendingMu.Lock()
+		delete(r.pending, req.ID)
+		r.pendingMu.Unlock()
+	}()
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.sendMsgDirect(data); err != nil {
+		return nil, err
+	}
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			if r.isClosed() {
+				return nil, ErrClosed
+			}
+			return nil, ErrDisconnected
+		}
+		if resp.Code == message.CodeOK {
+			r.trackSession(req.Cmd, req.Params)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctxErr(ctx)
+	}
+}
+
+// withTimeout ctx 没有设置截止时间时附加默认的请求超时时间
+func (r *Remote) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || r.opts.RequestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.opts.RequestTimeout)
+}
+
+// ctxErr 将 ctx 超时转换为 ErrTimeout, 便于调用方区分超时与主动取消
+func ctxErr(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return ctx.Err()
+}
+
+// route 分发读取到的完整消息: 推送消息交给推送回调, 带编号的响应交给等待的请求
+// 无法解析或者没有对应请求的消息记录日志之后丢弃, 读取协程不能因此阻塞
+func (r *Remote) route(content []byte) {
+	resp, err := message.DecodeResponse(content)
+	if err == nil && resp.Type == message.TypePush {
+		msg := &message.Message{}
+		if resp.DecodeData(msg) != nil {
+			return
+		}
+		r.trackOffset(msg.Topic, msg.Offset)
+		if r.opts.OnPush != nil {
+			r.opts.OnPush(msg)
+		}
+		return
+	}
+	if err != nil {
+		log.Println("Error reading 无法解析的消息, 已丢弃", err.Error())
+		return
+	}
+	if resp.ID == 0 {
+		log.Println("Error reading 没有请求编号的响应, 已丢弃", resp.Code, resp.Msg)
+		return
+	}
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	// 等待方已经超时放弃的响应直接丢弃
+	if ch, ok := r.pending[resp.ID]; ok {
+		select {
+		case ch <- resp:
+		default:
+		}
+	}
+}
+
+// HandleConnRead 读取协程, 连接断开之后按照配置自动重连
 func (r *Remote) HandleConnRead() {
+	for {
+		err := r.readLoop()
+		if r.isClosed() {
+			return
+		}
+		log.Println("Error reading", err.Error())
+		r.disconnect(err)
+		if !r.opts.Reconnect || !r.reconnect() {
+			r.Close()
+			return
+		}
+		go r.HandleHeartBeat(atomic.LoadUint64(&r.connGen))
+	}
+}
+
+// readLoop 循环读取当前连接中的消息, 直到读取出错
+func (r *Remote) readLoop() error {
 	// 循环阻塞读取消息, 读取到的消息追加存储到消息体中, 待消息收满之后, 发送给程序处理
 	var (
 		headBuf     []byte
@@ -66,13 +377,7 @@ func (r *Remote) HandleConnRead() {
 	for {
 		_, err := r.ReadFromConn()
 		if err != nil {
-			log.Println("Error reading", err.Error())
-			// 数据超出限制
-			if err.Error() == ConstBufferFullErr {
-				r.Close()
-				panic(ConstBufferFullErr)
-			}
-			return
+			return err
 		}
 		for {
 			// 刚开始的消息默认是消息头, 消息头一般设计为占用2字节或者4字节的长度, 用来保存整个消息的长度
@@ -86,43 +391,20 @@ func (r *Remote) HandleConnRead() {
 			contentSize = r.BytesToInt(headBuf)
 			// 如果缓冲区中的内容长度超过或者等于 消息头+消息体长度，那么后面相当于读取到了消息体的消息
 			if r.ReadBufLen() >= contentSize+ConstHeadSize {
-				// 将完整的消息体内容读取到缓冲区, 并发送到结果通道
+				// 将完整的消息体内容读取到缓冲区, 并分发到对应的接收方
 				contentBuf = r.Read(ConstHeadSize, contentSize)
-				if r.RequestChanClosed {
-					break
+				if contentBuf, err = packet.Decompress(packet.HeadCodecID(headBuf), contentBuf, r.maxReadBufLen); err != nil {
+					log.Println("Error reading", err.Error())
+					continue
 				}
-				r.ResponseChan <- contentBuf
-			}
-			break
-		}
-	}
-}
-
-func (r *Remote) HandleConnWrite() {
-	// 请求通道有数据就写入到远程
-	for req := range r.RequestChan {
-		if req != nil {
-			// 发送数据
-			err := r.sendMsgDirect(req)
-			if err == nil {
+				r.route(contentBuf)
 				continue
 			}
-			if !r.ResponseChanClosed {
-				r.ResponseChan <- []byte(err.Error())
-			} else {
-				log.Println("remote cmd send failed")
-			}
+			break
 		}
 	}
 }
 
-func (r *Remote) HandleHeartBeat() {
-	err := r.sendMsgDirect([]byte("heart beat"))
-	if err != nil {
-		log.Println("heart beat send err ", err.Error())
-	}
-}
-
 // leftShift 将读取缓冲区的有用字节前移
 func (r *Remote) readBufLeftShift() {
 	if r.readStart == 0 {
@@ -157,34 +439,19 @@ func (r *Remote) Read(offset, n int) []byte {
 	return buf
 }
 
-// SendMsgToRequestChan 向连接发送消息
-func (r *Remote) SendMsgToRequestChan(content []byte) error {
-	if r.RequestChanClosed {
-		return errors.New("remote request chan closed")
-	}
-	r.RequestChan <- content
-	return nil
-}
-
-// GetResponseFromChan 向连接发送消息
-func (r *Remote) GetResponseFromChan() ([]byte, error) {
-	if r.ResponseChanClosed {
-		return nil, errors.New("remote response chan closed")
-	}
-	select {
-	case msg := <-r.ResponseChan:
-		return msg, nil
-	case <-time.After(0 * time.Second):
-		return []byte(""), nil
-	}
-}
-
 // sendMessageDirect 向连接发送消息
 func (r *Remote) sendMsgDirect(content []byte) error {
 	headBytes := make([]byte, ConstHeadSize)
+	r.writeMu.Lock()
+	codec, minLen := r.codec, r.codecMinLen
+	r.writeMu.Unlock()
+	codecID, content := packet.Compress(codec, minLen, content)
 	contentSize := len(content)
 	headBytes = r.IntToBytes(contentSize)
+	headBytes[0] |= codecID << (packet.ConstCodecShift - 24)
+	r.writeMu.Lock()
 	_, err := r.Conn.Write(append(headBytes, content...))
+	r.writeMu.Unlock()
 	if err != nil {
 		return err
 	}
@@ -201,17 +468,56 @@ func (r *Remote) IntToBytes(n int) []byte {
 
 // BytesToInt 字节转换成整型
 func (r *Remote) BytesToInt(b []byte) int {
-	// (32位下如果超出整型上限可能有错误)
-	return int(binary.BigEndian.Uint32(b))
+	// 高4位为压缩算法编号, 不计入长度
+	return int(binary.BigEndian.Uint32(b) & packet.ConstLenMask)
 }
 
+// Close 关闭连接, 所有等待中的请求返回 ErrClosed, 可以重复调用
 func (r *Remote) Close() {
-	r.closed = true
-	r.RequestChanClosed = true
-	r.ResponseChanClosed = true
-	defer r.closeConn()
-	defer close(r.RequestChan)
-	defer close(r.ResponseChan)
+	r.closeOnce.Do(func() {
+		r.pendingMu.Lock()
+		r.closed = true
+		for id, ch := range r.pending {
+			close(ch)
+			delete(r.pending, id)
+		}
+		r.pendingMu.Unlock()
+		close(r.closeChan)
+		r.setState(StateClosed, nil)
+		r.closeConn()
+	})
+}
+
+// Addr 服务端地址
+func (r *Remote) Addr() string {
+	return r.addr
+}
+
+// User 认证的用户名, 未认证时为空
+func (r *Remote) User() string {
+	return r.opts.User
+}
+
+// TLS 是否使用 TLS 连接
+func (r *Remote) TLS() bool {
+	return r.opts.TLS
+}
+
+// Codec 与服务端协商的压缩算法, 不压缩时为空
+func (r *Remote) Codec() string {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	if r.codec == nil {
+		return ""
+	}
+	return r.codec.Name()
+}
+
+// isClosed 连接是否已经被关闭
+func (r *Remote) isClosed() bool {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	return r.closed
 }
 
 func (r *Remote) closeConn() {
@@ -234,9 +540,6 @@ func (r *Remote) ReadFromConn() (int, error) {
 	// 此处如果传入读取的缓冲区空闲长度为0，会陷入死循环， 所以前面做了扩容以及异常处理
 	n, err := r.Conn.Read(r.readBuf[r.readEnd:])
 	if err != nil {
-		if err == io.EOF {
-			r.Close()
-		}
 		return n, err
 	}
 	r.readEnd += n
@@ -0,0 +1,77 @@
+package remote
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/AdeMQ/protocol/message"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// writeFrame 按照 4 字节长度头加消息体的格式写入一条消息
+func writeFrame(conn net.Conn, content []byte) error {
+	head := make([]byte, ConstHeadSize)
+	binary.BigEndian.PutUint32(head, uint32(len(content)))
+	_, err := conn.Write(append(head, content...))
+	return err
+}
+
+// TestRouteDropsUnmatchedFrames 没有请求编号或者无法解析的消息不能阻塞读取协程, 之后的请求仍然可以完成
+func TestRouteDropsUnmatchedFrames(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// 在任何响应之前先发送原始字节以及没有编号的错误响应
+		_ = writeFrame(conn, []byte("消息长度超出缓冲区上限"))
+		_ = writeFrame(conn, message.Error(message.CodeBadRequest, "no id").Encode())
+		head := make([]byte, ConstHeadSize)
+		for {
+			if _, err := io.ReadFull(conn, head); err != nil {
+				return
+			}
+			body := make([]byte, binary.BigEndian.Uint32(head))
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			req, err := message.DecodeRequest(body)
+			if err != nil {
+				return
+			}
+			resp := message.OK("pong")
+			if len(req.Params) > 0 {
+				resp = message.OK(req.Params[0])
+			}
+			resp.ID = req.ID
+			if writeFrame(conn, resp.Encode()) != nil {
+				return
+			}
+		}
+	}()
+
+	r, err := Dial(context.Background(), ln.Addr().String(), WithoutReconnect())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		resp, err := r.Call(ctx, &message.Request{Cmd: "ping"})
+		cancel()
+		if err != nil {
+			t.Fatalf("第 %d 次请求失败: %v", i+1, err)
+		}
+		if resp.Code != message.CodeOK {
+			t.Fatalf("第 %d 次请求返回 %d %s", i+1, resp.Code, resp.Msg)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"github.com/AdeMQ/protocol/message"
+	"sync"
+	"time"
+)
+
+var ErrBufferFull = errors.New("ademq: async producer buffer is full")
+
+// AsyncConfig 异步生产者配置
+type AsyncConfig struct {
+	BatchSize int           // 每个批次的最大消息数, 默认 100
+	Linger    time.Duration // 批次中第一条消息最多等待的时间, 默认 10ms
+	Buffer    int           // 已提交但尚未完成的最大消息数, 默认 10000
+	// Block 缓冲区已满时 Publish 是否阻塞等待, 为 false 时立即返回 ErrBufferFull
+	Block bool
+}
+
+func (c *AsyncConfig) init() {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.Linger <= 0 {
+		c.Linger = 10 * time.Millisecond
+	}
+	if c.Buffer <= 0 {
+		c.Buffer = 10000
+	}
+}
+
+// Future 异步写入的结果
+type Future struct {
+	done     chan struct{}
+	offset   int64
+	err      error
+	callback func(offset int64, err error)
+}
+
+// Done 写入完成(成功或者失败)时关闭
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait 等待写入完成, 返回消息在主题中的偏移量
+func (f *Future) Wait(ctx context.Context) (int64, error) {
+	select {
+	case <-f.done:
+		return f.offset, f.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (f *Future) complete(offset int64, err error) {
+	f.offset, f.err = offset, err
+	close(f.done)
+	if f.callback != nil {
+		f.callback(offset, err)
+	}
+}
+
+// record 等待发送的消息
+type record struct {
+	topic   string
+	payload []byte
+	future  *Future
+}
+
+// batch 同一主题中等待发送的消息
+type batch struct {
+	seq     uint64
+	records []*record
+}
+
+// expiry 批次等待时间到期的通知
+type expiry struct {
+	topic string
+	seq   uint64
+}
+
+// AsyncProducer 异步生产者, 按照主题将消息合并为批次发送, 减少请求的往返次数
+// 批次达到 BatchSize 条消息或者第一条消息等待超过 Linger 时发送, 批次按照形成的顺序依次发送
+type AsyncProducer struct {
+	client *Client
+	conf   AsyncConfig
+	sem    chan struct{} // 限制已提交但尚未完成的消息数
+	in     chan *record
+	expire chan expiry
+	send   chan []*record
+	done   chan struct{} // 所有消息都发送完成之后关闭
+	mu     sync.RWMutex  // 保护 closed 以及关闭 in
+	closed bool
+
+	batches map[string]*batch // 只在合并协程中访问
+	seq     uint64
+}
+
+// AsyncProducer 创建异步生产者, 使用完成之后需要调用 Close 发送剩余的消息
+func (c *Client) AsyncProducer(conf AsyncConfig) *AsyncProducer {
+	conf.init()
+	p := &AsyncProducer{
+		client:  c,
+		conf:    conf,
+		sem:     make(chan struct{}, conf.Buffer),
+		in:      make(chan *record, conf.BatchSize),
+		expire:  make(chan expiry),
+		send:    make(chan []*record, 1),
+		done:    make(chan struct{}),
+		batches: make(map[string]*batch),
+	}
+	go p.collect()
+	go p.sender()
+	return p
+}
+
+// Publish 提交一条写入主题的消息, 写入结果通过返回的 Future 或者 callback 获取, callback 可以为 nil
+// callback 在发送协程中执行, 不能阻塞
+// 缓冲区已满时按照配置阻塞等待(直到 ctx 结束)或者返回 ErrBufferFull
+func (p *AsyncProducer) Publish(ctx context.Context, topic string, payload []byte, callback func(offset int64, err error)) (*Future, error) {
+	if p.conf.Block {
+		select {
+		case p.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else {
+		select {
+		case p.sem <- struct{}{}:
+		default:
+			return nil, ErrBufferFull
+		}
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		<-p.sem
+		return nil, ErrClosed
+	}
+	f := &Future{done: make(chan struct{}), callback: callback}
+	p.in <- &record{topic: topic, payload: payload, future: f}
+	return f, nil
+}
+
+// Close 停止接收新的消息, 发送所有剩余的批次并等待完成, ctx 结束时不再等待
+func (p *AsyncProducer) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.in)
+	}
+	p.mu.Unlock()
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// collect 合并协程, 将提交的消息按照主题合并为批次
+func (p *AsyncProducer) collect() {
+	for {
+		select {
+		case r, ok := <-p.in:
+			if !ok {
+				for topic := range p.batches {
+					p.flush(topic)
+				}
+				close(p.send)
+				return
+			}
+			p.add(r)
+		case e := <-p.expire:
+			if b, ok := p.batches[e.topic]; ok && b.seq == e.seq {
+				p.flush(e.topic)
+			}
+		}
+	}
+}
+
+func (p *AsyncProducer) add(r *record) {
+	b, ok := p.batches[r.topic]
+	if !ok {
+		p.seq++
+		b = &batch{seq: p.seq, records: make([]*record, 0, p.conf.BatchSize)}
+		p.batches[r.topic] = b
+		e := expiry{topic: r.topic, seq: b.seq}
+		time.AfterFunc(p.conf.Linger, func() {
+			select {
+			case p.expire <- e:
+			case <-p.done:
+			}
+		})
+	}
+	b.records = append(b.records, r)
+	if len(b.records) >= p.conf.BatchSize {
+		p.flush(r.topic)
+	}
+}
+
+func (p *AsyncProducer) flush(topic string) {
+	b := p.batches[topic]
+	delete(p.batches, topic)
+	p.send <- b.records
+}
+
+// sender 发送协程, 依次发送批次并完成每条消息的 Future
+func (p *AsyncProducer) sender() {
+	defer close(p.done)
+	for records := range p.send {
+		offsets, err := p.publish(records)
+		for i, r := range records {
+			if err != nil {
+				r.future.complete(0, err)
+			} else {
+				r.future.complete(offsets[i], nil)
+			}
+			<-p.sem
+		}
+	}
+}
+
+// publish 发送一个批次, 批次中的消息同时成功或者失败
+func (p *AsyncProducer) publish(records []*record) ([]int64, error) {
+	ret := struct {
+		Offsets []int64 `json:"offsets"`
+	}{}
+	payloads := make([][]byte, len(records))
+	for i, r := range records {
+		payloads[i] = r.payload
+	}
+	req := &message.Request{Cmd: "mpublish", Params: []string{records[0].topic}, Batch: payloads}
+	if err := p.client.do(context.Background(), req, &ret); err != nil {
+		return nil, err
+	}
+	if len(ret.Offsets) != len(records) {
+		return nil, errors.New("ademq: batch response size mismatch")
+	}
+	return ret.Offsets, nil
+}
@@ -0,0 +1,151 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AdeMQ/client/remote"
+	"github.com/AdeMQ/protocol/message"
+	"sync"
+)
+
+// Message 主题或者队列中的消息
+type Message = message.Message
+
+// Option 连接配置项
+type Option = remote.Option
+
+// State 连接状态
+type State = remote.State
+
+const (
+	StateConnected    = remote.StateConnected
+	StateDisconnected = remote.StateDisconnected
+	StateReconnecting = remote.StateReconnecting
+	StateClosed       = remote.StateClosed
+)
+
+var (
+	WithDialTimeout    = remote.WithDialTimeout
+	WithRequestTimeout = remote.WithRequestTimeout
+	WithAuth           = remote.WithAuth
+	WithCompression    = remote.WithCompression
+	WithTLS            = remote.WithTLS
+	WithStateHandler   = remote.WithStateHandler
+	WithReconnect      = remote.WithReconnect
+	WithoutReconnect   = remote.WithoutReconnect
+	WithHeartbeat      = remote.WithHeartbeat
+)
+
+var (
+	ErrClosed       = remote.ErrClosed
+	ErrDisconnected = remote.ErrDisconnected
+	ErrTimeout      = remote.ErrTimeout
+	ErrEmpty        = errors.New("ademq: queue is empty")
+)
+
+// Error 服务端返回的错误
+type Error struct {
+	Code int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ademq: %d %s", e.Code, e.Msg)
+}
+
+// Client AdeMQ 客户端, 可以被多个协程并发使用
+type Client struct {
+	remote   *remote.Remote
+	mu       sync.Mutex
+	closed   bool
+	subs     map[string]*subscription // 主题 => 订阅
+	handlers map[string]*queueHandler // 队列 => 推送消费的工作协程池
+	dropped  map[string]uint64        // 主题 => 订阅缓冲区已满时丢弃的消息数
+}
+
+// Dial 连接到 AdeMQ 服务, 地址以 unix: 开头时使用 unix socket
+func Dial(ctx context.Context, addr string, opts ...Option) (*Client, error) {
+	c := &Client{
+		subs:     make(map[string]*subscription),
+		handlers: make(map[string]*queueHandler),
+		dropped:  make(map[string]uint64),
+	}
+	opts = append(opts, remote.WithPushHandler(c.onPush))
+	r, err := remote.Dial(ctx, addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.remote = r
+	return c, nil
+}
+
+// State 获取当前的连接状态
+func (c *Client) State() State {
+	return c.remote.State()
+}
+
+// Producer 获取生产者
+func (c *Client) Producer() *Producer {
+	return &Producer{client: c}
+}
+
+// Consumer 获取消费者
+func (c *Client) Consumer() *Consumer {
+	return &Consumer{client: c}
+}
+
+// Close 关闭连接, 并停止所有订阅的处理协程以及推送消费的 Handle
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		for topic, sub := range c.subs {
+			sub.stop()
+			delete(c.subs, topic)
+		}
+		for _, h := range c.handlers {
+			close(h.stopped)
+		}
+	}
+	c.mu.Unlock()
+	c.remote.Close()
+	return nil
+}
+
+// do 发送请求并将响应的 Data 解析到 v 中, 服务端返回的错误码转换为 *Error
+func (c *Client) do(ctx context.Context, req *message.Request, v interface{}) error {
+	resp, err := c.remote.Call(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Code != message.CodeOK {
+		return &Error{Code: resp.Code, Msg: resp.Msg}
+	}
+	if v == nil || resp.Data == nil {
+		return nil
+	}
+	return resp.DecodeData(v)
+}
+
+// onPush 在连接的读取协程中执行, 将推送的消息转交给订阅的处理协程或者队列的工作协程池
+func (c *Client) onPush(msg *message.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if msg.Queue != "" {
+		if h, ok := c.handlers[msg.Queue]; ok && h.offer(msg) {
+			return
+		}
+		// 没有对应的 Handle 时为停止消费之后迟到的推送, 缓冲区已满时为重连之后重新注册超出了预取数量
+		// 都立即放回队列, 不能在读取协程中同步等待响应
+		go c.Consumer().requeue(msg)
+		return
+	}
+	if sub, ok := c.subs[msg.Topic]; ok {
+		// 不能在读取协程中阻塞等待, 否则处理函数中调用客户端的其他方法时无法收到响应
+		// 丢弃的消息数量通过 Consumer.Dropped 获取, 需要时可以使用 SubscribeFrom 从丢弃的偏移量重新订阅
+		if !sub.offer(msg) {
+			c.dropped[msg.Topic]++
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package sdk
+
+import (
+	"context"
+	"github.com/AdeMQ/protocol/message"
+	"strconv"
+)
+
+// subscriptionBuffer 每个订阅缓存的待处理消息数, 超出之后新推送的消息会被丢弃并计入 Consumer.Dropped
+const subscriptionBuffer = 1024
+
+// subscription 主题订阅, 推送的消息在独立的协程中按顺序交给处理函数
+type subscription struct {
+	msgChan chan *Message
+	handler func(*Message)
+}
+
+func newSubscription(handler func(*Message)) *subscription {
+	sub := &subscription{
+		msgChan: make(chan *Message, subscriptionBuffer),
+		handler: handler,
+	}
+	go func() {
+		for msg := range sub.msgChan {
+			sub.handler(msg)
+		}
+	}()
+	return sub
+}
+
+// offer 非阻塞地放入消息, 缓冲区已满时返回 false
+func (s *subscription) offer(msg *Message) bool {
+	select {
+	case s.msgChan <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *subscription) stop() {
+	close(s.msgChan)
+}
+
+// Consumer 消费者
+type Consumer struct {
+	client *Client
+}
+
+// Subscribe 订阅主题, 之后写入主题的消息会按顺序交给 handler 处理
+// handler 在每个订阅独立的协程中执行, 可以在其中调用客户端的其他方法
+func (c *Consumer) Subscribe(ctx context.Context, topic string, handler func(*Message)) error {
+	return c.subscribe(ctx, topic, -1, handler)
+}
+
+// SubscribeFrom 从指定的偏移量开始订阅主题, 服务端仍然保留的历史消息会先被补发
+func (c *Consumer) SubscribeFrom(ctx context.Context, topic string, offset int64, handler func(*Message)) error {
+	return c.subscribe(ctx, topic, offset, handler)
+}
+
+func (c *Consumer) subscribe(ctx context.Context, topic string, offset int64, handler func(*Message)) error {
+	cl := c.client
+	cl.mu.Lock()
+	if cl.closed {
+		cl.mu.Unlock()
+		return ErrClosed
+	}
+	if old, ok := cl.subs[topic]; ok {
+		old.stop()
+	}
+	// 先注册订阅再发送请求, 避免补发的历史消息先于响应到达时被丢弃
+	cl.subs[topic] = newSubscription(handler)
+	cl.mu.Unlock()
+
+	params := []string{topic}
+	if offset >= 0 {
+		params = append(params, strconv.FormatInt(offset, 10))
+	}
+	err := cl.do(ctx, &message.Request{Cmd: "subscribe", Params: params}, nil)
+	if err != nil {
+		c.removeSubscription(topic)
+	}
+	return err
+}
+
+// Unsubscribe 取消订阅主题
+func (c *Consumer) Unsubscribe(ctx context.Context, topic string) error {
+	c.removeSubscription(topic)
+	return c.client.do(ctx, &message.Request{Cmd: "unsubscribe", Params: []string{topic}}, nil)
+}
+
+func (c *Consumer) removeSubscription(topic string) {
+	c.client.mu.Lock()
+	defer c.client.mu.Unlock()
+	if sub, ok := c.client.subs[topic]; ok {
+		sub.stop()
+		delete(c.client.subs, topic)
+	}
+}
+
+// Dropped 获取主题因为订阅缓冲区已满而丢弃的消息总数, 处理函数的速度跟不上推送时增加
+func (c *Consumer) Dropped(topic string) uint64 {
+	c.client.mu.Lock()
+	defer c.client.mu.Unlock()
+	return c.client.dropped[topic]
+}
+
+// Pop 从队列取出一条消息, 队列为空时返回 ErrEmpty
+// 取出的消息需要通过 Ack 确认, 超时未确认的消息会被服务端重新投递
+func (c *Consumer) Pop(ctx context.Context, queue string) (*Message, error) {
+	var msg *Message
+	if err := c.client.do(ctx, &message.Request{Cmd: "pop", Params: []string{queue}}, &msg); err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, ErrEmpty
+	}
+	return msg, nil
+}
+
+// Ack 确认队列消息已经处理完成
+func (c *Consumer) Ack(ctx context.Context, msg *Message) error {
+	return c.client.do(ctx, &message.Request{Cmd: "ack", Params: []string{msg.Queue, strconv.FormatUint(msg.ID, 10)}}, nil)
+}
+
+// Nack 拒绝队列消息, 消息会重新放回队列头部
+func (c *Consumer) Nack(ctx context.Context, msg *Message) error {
+	return c.client.do(ctx, &message.Request{Cmd: "nack", Params: []string{msg.Queue, strconv.FormatUint(msg.ID, 10)}}, nil)
+}
@@ -0,0 +1,30 @@
+package sdk
+
+import (
+	"testing"
+)
+
+func TestSubscriptionDropped(t *testing.T) {
+	c := &Client{subs: make(map[string]*subscription), dropped: make(map[string]uint64)}
+	started, block := make(chan struct{}, 1), make(chan struct{})
+	defer close(block)
+	// 处理函数阻塞, 第一条消息被取出之后缓冲区可以再容纳 subscriptionBuffer 条
+	c.subs["t"] = newSubscription(func(*Message) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-block
+	})
+	c.onPush(&Message{Topic: "t"})
+	<-started
+	for i := 0; i < subscriptionBuffer+3; i++ {
+		c.onPush(&Message{Topic: "t", Offset: int64(i + 1)})
+	}
+	if n := c.Consumer().Dropped("t"); n != 3 {
+		t.Fatalf("dropped = %d, want 3", n)
+	}
+	if n := c.Consumer().Dropped("other"); n != 0 {
+		t.Fatalf("dropped = %d, want 0", n)
+	}
+}
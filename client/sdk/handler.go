@@ -0,0 +1,111 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"github.com/AdeMQ/protocol/message"
+	"log"
+	"strconv"
+	"sync"
+)
+
+var ErrHandling = errors.New("ademq: queue is already being handled")
+
+// queueHandler 推送消费的工作协程池, 推送的队列消息交给固定数量的工作协程处理
+type queueHandler struct {
+	msgChan chan *Message
+	stopped chan struct{} // 客户端关闭时关闭, 通知 Handle 退出
+}
+
+// offer 非阻塞地放入消息, 缓冲区已满时返回 false, 调用时需要持有客户端的锁
+// 服务端按照预取数量推送, 正常情况下缓冲区不会满, 只有重连之后重新注册时可能超出
+func (h *queueHandler) offer(msg *Message) bool {
+	select {
+	case h.msgChan <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Handle 以推送方式消费队列, 服务端推送的消息交给 concurrency 个工作协程并发处理
+// handler 返回 nil 时自动确认消息, 返回 error 时拒绝消息使其重新投递
+// Handle 会阻塞直到 ctx 结束, 之后停止接收新的消息, 拒绝尚未开始处理的消息, 并等待正在处理的消息完成
+func (c *Consumer) Handle(ctx context.Context, queue string, handler func(*Message) error, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	cl := c.client
+	h := &queueHandler{
+		msgChan: make(chan *Message, concurrency),
+		stopped: make(chan struct{}),
+	}
+	cl.mu.Lock()
+	if cl.closed {
+		cl.mu.Unlock()
+		return ErrClosed
+	}
+	if _, ok := cl.handlers[queue]; ok {
+		cl.mu.Unlock()
+		return ErrHandling
+	}
+	cl.handlers[queue] = h
+	cl.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range h.msgChan {
+				// ctx 结束之后尚未开始处理的消息直接拒绝, 交给其他消费者
+				if ctx.Err() != nil {
+					c.requeue(msg)
+					continue
+				}
+				c.settle(msg, handler(msg))
+			}
+		}()
+	}
+	req := &message.Request{Cmd: "consume", Params: []string{queue, strconv.Itoa(concurrency)}}
+	err := cl.do(ctx, req, nil)
+	if err == nil {
+		select {
+		case <-ctx.Done():
+			// 服务端的响应直接写入连接, 推送的消息则经过连接的发送队列, 所以 unconsume 之前已经推送的消息可能在响应之后到达
+			// 这些迟到的消息由 onPush 通过 requeue 放回队列
+			if err := cl.do(context.Background(), &message.Request{Cmd: "unconsume", Params: []string{queue}}, nil); err != nil {
+				log.Println("ademq: unconsume failed", queue, err)
+			}
+		case <-h.stopped:
+			err = ErrClosed
+		}
+	}
+	cl.mu.Lock()
+	delete(cl.handlers, queue)
+	close(h.msgChan)
+	cl.mu.Unlock()
+	wg.Wait()
+	return err
+}
+
+// requeue 拒绝不再处理的推送消息使其立即重新投递给其他消费者
+// 停止消费之后迟到的推送属于正常情况, 连接已经断开时服务端会自动放回未确认的消息, 都不记录错误
+func (c *Consumer) requeue(msg *Message) {
+	if err := c.Nack(context.Background(), msg); err != nil && err != ErrClosed && err != ErrDisconnected {
+		log.Println("ademq: requeue message failed", msg.Queue, msg.ID, err)
+	}
+}
+
+// settle 根据处理结果确认或者拒绝消息
+func (c *Consumer) settle(msg *Message, err error) {
+	ctx := context.Background()
+	if err == nil {
+		err = c.Ack(ctx, msg)
+	} else {
+		err = c.Nack(ctx, msg)
+	}
+	if err != nil {
+		log.Println("ademq: settle message failed", msg.Queue, msg.ID, err)
+	}
+}
@@ -0,0 +1,35 @@
+package sdk
+
+import (
+	"context"
+	"github.com/AdeMQ/protocol/message"
+)
+
+// Producer 生产者
+type Producer struct {
+	client *Client
+}
+
+// Publish 向主题写入消息, 返回消息在主题中的偏移量
+func (p *Producer) Publish(ctx context.Context, topic string, payload []byte) (int64, error) {
+	ret := struct {
+		Offset int64 `json:"offset"`
+	}{}
+	req := &message.Request{Cmd: "publish", Params: []string{topic}, Payload: payload}
+	if err := p.client.do(ctx, req, &ret); err != nil {
+		return 0, err
+	}
+	return ret.Offset, nil
+}
+
+// Push 向队列写入消息, 返回消息在队列中的编号
+func (p *Producer) Push(ctx context.Context, queue string, payload []byte) (uint64, error) {
+	ret := struct {
+		ID uint64 `json:"id"`
+	}{}
+	req := &message.Request{Cmd: "push", Params: []string{queue}, Payload: payload}
+	if err := p.client.do(ctx, req, &ret); err != nil {
+		return 0, err
+	}
+	return ret.ID, nil
+}
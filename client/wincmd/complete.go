@@ -0,0 +1,97 @@
+package wincmd
+
+import (
+	"context"
+	"github.com/AdeMQ/client/handler/commands"
+	"github.com/AdeMQ/protocol/message"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// completionTTL 主题与队列名称的缓存时间, 避免每次按下 Tab 都请求服务端
+const completionTTL = 5 * time.Second
+
+// resourceCache 从服务端获取的主题与队列名称
+type resourceCache struct {
+	mu        sync.Mutex
+	names     []string
+	fetchedAt time.Time
+}
+
+// complete 补全光标所在的单词: 第一个单词补全命令名称, help 之后补全命令名称, info 之后补全分类, - 开头的单词补全选项, 其余补全主题与队列名称
+func (wc *WinClient) complete(line string) []string {
+	fields := strings.Fields(line)
+	word := ""
+	if len(fields) > 0 && !strings.HasSuffix(line, " ") {
+		word = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+	var names []string
+	switch {
+	case len(fields) == 0 || (len(fields) == 1 && strings.ToLower(fields[0]) == commands.CmdHelp.Name):
+		for _, cmd := range wc.Dispatcher.Commands {
+			names = append(names, cmd.Name)
+		}
+	case strings.HasPrefix(word, "-"):
+		// 补全命令定义中的选项
+		if cmd, ok := wc.Dispatcher.Lookup[strings.ToLower(fields[0])]; ok {
+			for _, f := range cmd.Flags {
+				names = append(names, f.Name)
+			}
+		}
+	case len(fields) == 1 && strings.ToLower(fields[0]) == commands.CmdInfo.Name:
+		names = message.InfoSections
+	default:
+		names = wc.resourceNames()
+	}
+	var candidates []string
+	for _, name := range names {
+		if strings.HasPrefix(name, word) {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// reset 清空缓存, 连接到其他服务端之后需要重新获取
+func (c *resourceCache) reset() {
+	c.mu.Lock()
+	c.names, c.fetchedAt = nil, time.Time{}
+	c.mu.Unlock()
+}
+
+// resourceNames 当前用户可以访问的主题与队列名称, 请求失败时返回缓存的结果
+func (wc *WinClient) resourceNames() []string {
+	c := &wc.resources
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := wc.current()
+	if r == nil || time.Since(c.fetchedAt) < completionTTL {
+		return c.names
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	seen := make(map[string]bool)
+	var names []string
+	for _, resource := range []string{"topic", "queue"} {
+		resp, err := r.Call(ctx, &message.Request{Cmd: "list", Params: []string{resource}})
+		if err != nil || resp.Code != message.CodeOK {
+			return c.names
+		}
+		var ret []string
+		if resp.DecodeData(&ret) != nil {
+			return c.names
+		}
+		for _, name := range ret {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	c.names, c.fetchedAt = names, time.Now()
+	return names
+}
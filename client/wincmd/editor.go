@@ -0,0 +1,419 @@
+package wincmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/AdeMQ/client/output"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// errInterrupt 输入过程中按下 Ctrl-C, 放弃当前输入
+var errInterrupt = errors.New("interrupt")
+
+// 控制字符
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEsc       = 27
+	keyDelete    = 127
+
+	// 以下为转义序列转换之后的按键, 取值超出 unicode 范围避免与输入的字符冲突
+	keyUp = utf8.MaxRune + 1 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDel
+	keyUnknown
+)
+
+// LineEditor 终端行编辑器, 在 raw 模式下逐个读取按键
+// 支持光标移动、历史命令导航(上下方向键)、Ctrl-R 反向搜索以及 Tab 补全
+// 标准输入不是终端或者当前系统不支持 raw 模式时, 退化为按行读取
+type LineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	fd       int
+	history  func() []string            // 历史命令, 从旧到新
+	complete func(line string) []string // 根据光标之前的内容获取光标所在单词的补全候选项
+
+	buf    []rune
+	pos    int // 光标在 buf 中的位置
+	prompt string
+}
+
+// NewLineEditor 创建终端行编辑器
+func NewLineEditor(in *bufio.Reader, out io.Writer, history func() []string, complete func(line string) []string) *LineEditor {
+	return &LineEditor{
+		in:       in,
+		out:      out,
+		fd:       int(os.Stdin.Fd()),
+		history:  history,
+		complete: complete,
+	}
+}
+
+// ReadLine 输出提示符并读取一行输入, 返回的内容不包含换行
+// 空行时按下 Ctrl-D 返回 io.EOF, 按下 Ctrl-C 返回 errInterrupt
+func (e *LineEditor) ReadLine(prompt string) (string, error) {
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		// 不支持 raw 模式时按行读取
+		_, _ = fmt.Fprint(e.out, prompt)
+		line, err := e.in.ReadString('\n')
+		if err == io.EOF {
+			_, _ = fmt.Fprintln(e.out)
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	defer restore()
+
+	e.buf, e.pos, e.prompt = e.buf[:0], 0, prompt
+	e.refresh()
+	history := e.history()
+	histIdx := len(history) // 当前浏览的历史位置, len(history) 表示正在编辑的新行
+	saved := ""             // 开始浏览历史之前正在编辑的内容
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case keyEnter, '\n':
+			_, _ = fmt.Fprint(e.out, "\r\n")
+			return string(e.buf), nil
+		case keyCtrlC:
+			_, _ = fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupt
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				_, _ = fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyDel:
+			e.deleteAt(e.pos)
+		case keyLeft, keyCtrlB:
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyRight, keyCtrlF:
+			if e.pos < len(e.buf) {
+				e.pos++
+			}
+		case keyHome, keyCtrlA:
+			e.pos = 0
+		case keyEnd, keyCtrlE:
+			e.pos = len(e.buf)
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+		case keyCtrlU:
+			e.buf = append(e.buf[:0], e.buf[e.pos:]...)
+			e.pos = 0
+		case keyCtrlW:
+			start := e.wordStart()
+			e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+			e.pos = start
+		case keyCtrlL:
+			_, _ = fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyUp, keyCtrlP, keyDown, keyCtrlN:
+			if histIdx == len(history) {
+				saved = string(e.buf)
+			}
+			histIdx = e.navigate(history, histIdx, key == keyUp || key == keyCtrlP)
+			if histIdx == len(history) {
+				e.setLine(saved)
+			} else {
+				e.setLine(history[histIdx])
+			}
+		case keyCtrlR:
+			line, accepted, err := e.search(history)
+			if err != nil {
+				return "", err
+			}
+			e.setLine(line)
+			if accepted {
+				e.refresh()
+				_, _ = fmt.Fprint(e.out, "\r\n")
+				return line, nil
+			}
+		case keyTab:
+			e.completeWord()
+		case keyEsc, keyUnknown:
+		default:
+			if key >= ' ' {
+				e.insert(key)
+			}
+		}
+		e.refresh()
+	}
+}
+
+// navigate 在历史命令中向上或者向下移动, 跳过多行的命令, 返回新的位置
+func (e *LineEditor) navigate(history []string, idx int, up bool) int {
+	for {
+		if up {
+			if idx == 0 {
+				return idx
+			}
+			idx--
+		} else {
+			if idx >= len(history) {
+				return len(history)
+			}
+			idx++
+			if idx == len(history) {
+				return idx
+			}
+		}
+		if !strings.Contains(history[idx], "\n") {
+			return idx
+		}
+	}
+}
+
+// search Ctrl-R 反向搜索历史命令, 再次按下 Ctrl-R 继续向前搜索
+// 按下回车时 accepted 为 true 并直接执行匹配的命令, 按下其他编辑键时将匹配的命令放入编辑区, Ctrl-G 或者 Esc 放弃搜索
+func (e *LineEditor) search(history []string) (line string, accepted bool, err error) {
+	original := string(e.buf)
+	query := []rune{}
+	match, matchIdx := "", len(history)
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(history[i], string(query)) && !strings.Contains(history[i], "\n") {
+				match, matchIdx = history[i], i
+				return
+			}
+		}
+	}
+	for {
+		label := "reverse-i-search"
+		if len(query) > 0 && !strings.Contains(match, string(query)) {
+			label = "failed reverse-i-search"
+		}
+		_, _ = fmt.Fprintf(e.out, "\r\x1b[K(%s)`%s': %s", label, string(query), match)
+		key, err := e.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch key {
+		case keyCtrlR:
+			if matchIdx > 0 {
+				find(matchIdx - 1)
+			}
+		case keyBackspace, keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match, matchIdx = "", len(history)
+				find(len(history) - 1)
+			}
+		case keyEnter, '\n':
+			if match == "" {
+				return original, false, nil
+			}
+			return match, true, nil
+		case keyCtrlC:
+			return "", false, nil
+		case keyCtrlG, keyEsc:
+			return original, false, nil
+		default:
+			if key >= ' ' && key <= utf8.MaxRune && key != keyDelete {
+				// 先从当前匹配的位置继续搜索, 当前位置不再匹配时从最新的命令重新搜索
+				query = append(query, key)
+				if matchIdx < len(history) {
+					find(matchIdx)
+				}
+				if !strings.Contains(match, string(query)) {
+					find(len(history) - 1)
+				}
+				continue
+			}
+			if match == "" {
+				return original, false, nil
+			}
+			return match, false, nil
+		}
+	}
+}
+
+// completeWord 补全光标所在的单词
+// 只有一个候选项时直接补全并追加空格, 多个候选项时补全公共前缀, 无法继续补全时列出所有候选项
+func (e *LineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	start := e.pos
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+	word := string(e.buf[start:e.pos])
+	candidates := e.complete(string(e.buf[:e.pos]))
+	if len(candidates) == 0 {
+		return
+	}
+	replace := commonPrefix(candidates)
+	if len(candidates) == 1 {
+		replace += " "
+	}
+	if replace != word && strings.HasPrefix(replace, word) {
+		tail := append([]rune(replace), e.buf[e.pos:]...)
+		e.buf = append(e.buf[:start], tail...)
+		e.pos = start + utf8.RuneCountInString(replace)
+		return
+	}
+	_, _ = fmt.Fprint(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+}
+
+// wordStart 光标之前的单词的起始位置
+func (e *LineEditor) wordStart() int {
+	i := e.pos
+	for i > 0 && e.buf[i-1] == ' ' {
+		i--
+	}
+	for i > 0 && e.buf[i-1] != ' ' {
+		i--
+	}
+	return i
+}
+
+func (e *LineEditor) insert(r rune) {
+	e.buf = append(e.buf, 0)
+	copy(e.buf[e.pos+1:], e.buf[e.pos:])
+	e.buf[e.pos] = r
+	e.pos++
+}
+
+func (e *LineEditor) deleteAt(i int) {
+	if i < len(e.buf) {
+		e.buf = append(e.buf[:i], e.buf[i+1:]...)
+	}
+}
+
+func (e *LineEditor) setLine(line string) {
+	e.buf = append(e.buf[:0], []rune(line)...)
+	e.pos = len(e.buf)
+}
+
+// refresh 重新绘制当前行并移动光标
+func (e *LineEditor) refresh() {
+	line := string(e.buf)
+	back := runesWidth(e.buf[e.pos:])
+	s := "\r\x1b[K" + e.prompt + line
+	if back > 0 {
+		s += fmt.Sprintf("\x1b[%dD", back)
+	}
+	_, _ = fmt.Fprint(e.out, s)
+}
+
+// readKey 读取一个按键, 方向键等转义序列转换为对应的按键常量
+func (e *LineEditor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEsc {
+		return r, err
+	}
+	// 单独的 Esc 之后没有后续的字节
+	if e.in.Buffered() == 0 {
+		return keyEsc, nil
+	}
+	b, err := e.in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != '[' && b != 'O' {
+		return keyUnknown, nil
+	}
+	b, err = e.in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch b {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+	// ESC [ n ~ 形式的按键
+	if b < '0' || b > '9' {
+		return keyUnknown, nil
+	}
+	n := b
+	for {
+		b, err = e.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == '~' {
+			break
+		}
+		if b < '0' || b > '9' {
+			return keyUnknown, nil
+		}
+		n = 0 // 多位数字的序列都不处理
+	}
+	switch n {
+	case '1', '7':
+		return keyHome, nil
+	case '4', '8':
+		return keyEnd, nil
+	case '3':
+		return keyDel, nil
+	}
+	return keyUnknown, nil
+}
+
+// commonPrefix 所有候选项的公共前缀
+func commonPrefix(items []string) string {
+	prefix := items[0]
+	for _, item := range items[1:] {
+		for !strings.HasPrefix(item, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// 避免截断多字节字符
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// runesWidth 终端显示宽度, 东亚宽字符占用2列, 规则与表格输出相同
+func runesWidth(rs []rune) int {
+	w := 0
+	for _, r := range rs {
+		w += output.RuneWidth(r)
+	}
+	return w
+}
@@ -1 +1,15 @@
 package wincmd
+
+import (
+	"flag"
+	"github.com/AdeMQ/client/handler"
+)
+
+var (
+	execCmd    = flag.String("e", "", "执行单条命令之后退出, 例如 -e \"ping -c 3\"")
+	outFormat  = flag.String("output", "text", "命令结果的输出格式 text|json|table, 运行过程中可以通过 format 命令修改")
+	scriptFile = flag.String("f", "", "按行执行脚本文件中的命令之后退出, 空行以及 # 开头的行会被忽略")
+
+	historyFile = flag.String("historyFile", "~/.ademq_history", "交互模式的历史记录文件, 为空表示不保存历史记录")
+	historySize = flag.Int("historySize", handler.ConstHistorySize, "保留的历史记录条数")
+)
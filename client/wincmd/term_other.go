@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package wincmd
+
+import "errors"
+
+// makeRaw 当前系统不支持 raw 模式, 行编辑器退化为按行读取
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw mode is not supported")
+}
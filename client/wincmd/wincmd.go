@@ -2,57 +2,371 @@ package wincmd
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/AdeMQ/client/handler"
+	"github.com/AdeMQ/client/output"
 	"github.com/AdeMQ/client/remote"
+	"github.com/AdeMQ/protocol/message"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 非交互模式的退出码
+const (
+	ExitOK    = 0 // 所有命令执行成功
+	ExitError = 1 // 连接失败或者有命令执行失败
 )
 
 // WinClient 交互式命令行客户端
 type WinClient struct {
-	Reader     *bufio.Reader
-	Parser     *handler.Parser
-	Dispatcher *handler.Dispatcher
-	Remote     *remote.Remote
+	Reader        *bufio.Reader
+	Parser        *handler.Parser
+	Dispatcher    *handler.Dispatcher
+	connMu        sync.Mutex
+	conn          *remote.Remote // 当前的服务端连接, 未连接时为 nil, 连接的回调协程中同样会读取, 需要通过 current 获取
+	interactive   bool           // 是否为交互模式, 非交互模式不输出命令提示符
+	script        bool           // 是否正在按行执行脚本, 执行脚本时 Ctrl-C 直接结束进程
+	disconnecting int32          // 为1时正在主动断开连接, 不再提示连接状态的变化, 在连接的回调协程中读取
+	editor        *LineEditor
+	resources     resourceCache // Tab 补全使用的主题与队列名称
+	pushMu        sync.Mutex
+	listener      *pushListener // 正在接收推送消息的命令, 没有时推送的消息被丢弃
 }
 
-// NewWinClient 创建命令行客户端
-func NewWinClient() *WinClient {
-	return &WinClient{
+// pushListener 推送消息的接收方
+type pushListener struct {
+	msgs chan *message.Message
+	done chan struct{} // 停止接收时关闭
+}
+
+// NewWinClient 创建命令行客户端, 并连接到 -address 指定的服务端
+// 连接失败或者地址为空时仍然可以启动, 之后可以通过 connect 命令连接, 期间只能执行本地命令
+func NewWinClient() (*WinClient, error) {
+	printer, err := output.NewPrinter(*outFormat, os.Stdout, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	wc := &WinClient{
 		Reader:     bufio.NewReader(os.Stdin),
 		Parser:     handler.NewParser(),
-		Dispatcher: handler.NewDispatcher(),
-		Remote:     remote.NewRemote(),
+		Dispatcher: handler.NewDispatcher(printer, handler.NewCmdHistory(*historySize, "")),
 	}
+	wc.Dispatcher.Connector = wc
+	wc.Dispatcher.Listener = wc
+	wc.Dispatcher.Stdin = wc.Reader
+	wc.editor = NewLineEditor(wc.Reader, os.Stdout, wc.history, wc.complete)
+	if addr := remote.DefaultAddr(); addr != "" {
+		if err = wc.Connect(addr, "", ""); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "服务器连接失败: "+err.Error())
+		}
+	}
+	return wc, nil
 }
 
-// Run 命令行客户端启动运行
-func (wc *WinClient) Run() {
-	// 链接到服务端
-	wc.Remote.Init()
+// Connect 连接到指定地址的服务端, 连接成功之后关闭原有的连接, 连接失败时保留原有的连接
+// user 为空时使用 -user 与 -password 参数指定的用户
+func (wc *WinClient) Connect(addr, user, password string) error {
+	opts := []remote.Option{remote.WithStateHandler(wc.stateHandler()), remote.WithPushHandler(wc.onPush)}
+	if user != "" {
+		opts = append(opts, remote.WithAuth(user, password))
+	}
+	r, err := remote.NewRemoteTo(addr, opts...)
+	if err != nil {
+		return err
+	}
+	_ = wc.Disconnect()
+	wc.connMu.Lock()
+	wc.conn = r
+	wc.connMu.Unlock()
+	wc.resources.reset()
+	return nil
+}
 
-	// 阻塞读取命令行数据
-	for {
-		fmt.Print("$ ")
-		cmdStr, err := wc.Reader.ReadString('\n')
+// Disconnect 断开当前的连接, 未连接时返回错误
+func (wc *WinClient) Disconnect() error {
+	wc.connMu.Lock()
+	r := wc.conn
+	wc.conn = nil
+	wc.connMu.Unlock()
+	if r == nil {
+		return errors.New("未连接到服务器")
+	}
+	atomic.StoreInt32(&wc.disconnecting, 1)
+	r.Close()
+	atomic.StoreInt32(&wc.disconnecting, 0)
+	wc.resources.reset()
+	return nil
+}
+
+// current 当前的服务端连接, 未连接时为 nil
+func (wc *WinClient) current() *remote.Remote {
+	wc.connMu.Lock()
+	defer wc.connMu.Unlock()
+	return wc.conn
+}
+
+// Listen 开始接收推送的消息, 调用返回的 stop 之后不再接收
+func (wc *WinClient) Listen() (<-chan *message.Message, func()) {
+	l := &pushListener{msgs: make(chan *message.Message, 256), done: make(chan struct{})}
+	wc.pushMu.Lock()
+	wc.listener = l
+	wc.pushMu.Unlock()
+	return l.msgs, func() {
+		wc.pushMu.Lock()
+		if wc.listener == l {
+			wc.listener = nil
+		}
+		wc.pushMu.Unlock()
+		close(l.done)
+	}
+}
+
+// onPush 在连接的读取协程中执行, 将推送的消息转交给正在接收的命令
+// 命令输出较慢时阻塞读取协程, 由服务端的发送缓冲区限制推送的速度
+func (wc *WinClient) onPush(msg *message.Message) {
+	wc.pushMu.Lock()
+	l := wc.listener
+	wc.pushMu.Unlock()
+	if l == nil {
+		return
+	}
+	select {
+	case l.msgs <- msg:
+	case <-l.done:
+	}
+}
+
+// stateHandler 返回连接状态变化的回调, 每个连接单独记录上一次的状态
+// 连接状态变化时提示用户, 重连过程中的多次尝试只提示一次
+// 回调在连接的读取协程以及调用 Close 的协程中执行, 上一次的状态需要加锁
+func (wc *WinClient) stateHandler() func(remote.State, error) {
+	var mu sync.Mutex
+	last := remote.StateConnected
+	return func(state remote.State, err error) {
+		mu.Lock()
+		prev := last
+		last = state
+		mu.Unlock()
+		if atomic.LoadInt32(&wc.disconnecting) == 1 {
+			return
+		}
+		switch {
+		case state == remote.StateDisconnected:
+			_, _ = fmt.Fprintln(os.Stderr, "\n连接已断开:", err)
+		case state == remote.StateReconnecting && prev != remote.StateReconnecting:
+			_, _ = fmt.Fprintln(os.Stderr, "正在重新连接...")
+		case state == remote.StateConnected && prev == remote.StateReconnecting:
+			_, _ = fmt.Fprint(os.Stderr, "已重新连接\n"+wc.prompt())
+		case state == remote.StateClosed && prev != remote.StateConnected:
+			_, _ = fmt.Fprintln(os.Stderr, "重连失败, 连接已关闭, 可以使用 connect 命令重新连接")
+		}
+	}
+}
+
+// prompt 命令提示符, 显示当前连接的服务端地址, 未连接或者连接异常时显示连接状态
+func (wc *WinClient) prompt() string {
+	if !wc.interactive {
+		return ""
+	}
+	r := wc.current()
+	switch {
+	case r == nil:
+		return "(not connected) $ "
+	case r.State() == remote.StateConnected:
+		return r.Addr() + " $ "
+	}
+	return r.Addr() + " (" + r.State().String() + ") $ "
+}
+
+// Start 按照命令行参数选择运行模式, 返回进程的退出码
+// -e 执行单条命令, 参数之后的内容作为单条命令执行, -f 执行脚本文件, 标准输入不是终端(管道或者重定向)时按行执行标准输入中的命令, 否则进入交互模式
+// 非交互模式遇到第一条执行失败的命令时立即停止并返回 ExitError
+func (wc *WinClient) Start() int {
+	defer func() {
+		_ = wc.Disconnect()
+	}()
+	switch {
+	case *execCmd != "":
+		if !wc.handleCommand(*execCmd) {
+			return ExitError
+		}
+		return ExitOK
+	case flag.NArg() > 0:
+		// 命令行参数之后的内容作为单条命令执行, 例如 client bench --duration 30s, 参数已经由 shell 切分, 不需要再解析
+		args := flag.Args()
+		cmd := &handler.ParsedCmd{Cmd: strings.ToLower(args[0]), Params: args[1:], Origin: strings.Join(args, " ")}
+		if !wc.execute(cmd) {
+			return ExitError
+		}
+		return ExitOK
+	case *scriptFile != "":
+		f, err := os.Open(*scriptFile)
 		if err != nil {
-			_, _ = fmt.Fprintln(os.Stderr, err)
+			_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
+			return ExitError
 		}
-		cmdStr = strings.TrimSuffix(cmdStr, "\n")
+		defer f.Close()
+		return wc.RunScript(f)
+	case !isTerminal(os.Stdin):
+		// 命令本身从标准输入读取, publish - 不能再读取标准输入, 否则会读走之后的命令
+		wc.Dispatcher.Stdin = nil
+		return wc.RunScript(os.Stdin)
+	}
+	// 只有交互模式才持久化历史记录, 避免脚本中的命令混入
+	wc.interactive = true
+	wc.Dispatcher.History = handler.NewCmdHistory(*historySize, expandHome(*historyFile))
+	wc.Run()
+	return ExitOK
+}
+
+// Run 命令行客户端启动运行, 读取到 EOF(Ctrl-D) 时退出
+func (wc *WinClient) Run() {
+	// 阻塞读取命令行数据, 续行的提示符为 >
+	for {
+		prompt := wc.prompt()
+		cmdStr, ok := wc.readCommand(func() (string, error) {
+			line, err := wc.editor.ReadLine(prompt)
+			prompt = "> "
+			return line, err
+		})
 		if cmdStr != "" {
 			wc.handleCommand(cmdStr)
 		}
+		if !ok {
+			return
+		}
 	}
 }
 
-func (wc *WinClient) handleCommand(cmdStr string) {
+// RunScript 按行执行命令, 空行以及 # 开头的注释行会被忽略, 遇到第一条执行失败的命令时停止
+func (wc *WinClient) RunScript(r io.Reader) int {
+	wc.script = true
+	reader := bufio.NewReader(r)
+	line := 0
+	for {
+		start := line + 1
+		cmdStr, ok := wc.readCommand(func() (string, error) {
+			line++
+			return reader.ReadString('\n')
+		})
+		if cmdStr != "" && !strings.HasPrefix(cmdStr, "#") && !wc.handleCommand(cmdStr) {
+			_, _ = fmt.Fprintf(os.Stderr, "第 %d 行命令执行失败: %s\n", start, cmdStr)
+			return ExitError
+		}
+		if !ok {
+			return ExitOK
+		}
+	}
+}
+
+// readCommand 读取一条完整的命令, 行尾为反斜杠或者 heredoc 未结束时继续读取下一行
+// 读取到 EOF 或者出错时 ok 为 false, 已经读取的内容仍然返回, 输入过程中按下 Ctrl-C 时放弃已经读取的内容
+func (wc *WinClient) readCommand(readLine func() (string, error)) (cmdStr string, ok bool) {
+	line, err := readLine()
+	cmdStr = strings.TrimSpace(line)
+	for err == nil && cmdStr != "" && wc.Parser.Incomplete(cmdStr) {
+		// 续行的内容可能是消息体, 只去掉行尾的换行
+		line, err = readLine()
+		if line == "" && err != nil {
+			break
+		}
+		cmdStr += "\n" + strings.TrimRight(line, "\r\n")
+	}
+	if err == errInterrupt {
+		return "", true
+	}
+	if err != nil && err != io.EOF {
+		_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	return cmdStr, err == nil
+}
+
+// handleCommand 执行一条命令并输出结果, 命令执行失败时返回 false
+func (wc *WinClient) handleCommand(cmdStr string) bool {
+	// 展开 !n 以及 !! 并显示实际执行的命令
+	expanded, err := wc.Dispatcher.History.Expand(cmdStr)
+	if err != nil {
+		return wc.Dispatcher.Printer.Print("Error: " + err.Error())
+	}
+	if expanded != cmdStr {
+		_, _ = fmt.Fprintln(os.Stderr, expanded)
+		cmdStr = expanded
+	}
 	// 处理命令行逻辑
-	cmd := wc.Parser.Parse(cmdStr)
-	ret := wc.Dispatcher.Dispatch(cmd, wc.Remote)
-	// 得到结果直接输出到标准输出
-	if ret == "" {
-		return
+	cmd, err := wc.Parser.Parse(cmdStr)
+	if err != nil {
+		return wc.Dispatcher.Printer.Print("Error: 命令解析失败: " + err.Error())
+	}
+	return wc.execute(cmd)
+}
+
+// execute 执行解析之后的命令并输出结果, 命令执行失败时返回 false
+func (wc *WinClient) execute(cmd *handler.ParsedCmd) bool {
+	ctx, cancel := wc.interruptContext()
+	defer cancel()
+	ret := wc.Dispatcher.Dispatch(ctx, cmd, wc.current())
+	// 按照输出格式输出到标准输出, 失败的结果输出到标准错误
+	return wc.Dispatcher.Printer.Print(ret)
+}
+
+// interruptContext 命令执行期间按下 Ctrl-C 时取消 ctx, 结束 subscribe 与 bench 等持续执行的命令, 交互模式下返回命令提示符
+// 执行脚本时保持默认的信号处理, Ctrl-C 直接结束进程
+func (wc *WinClient) interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if wc.script {
+		return ctx, cancel
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			_, _ = fmt.Fprintln(os.Stderr)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sig)
+		cancel()
+	}
+}
+
+// history 历史命令, 从旧到新
+func (wc *WinClient) history() []string {
+	all := wc.Dispatcher.History.All()
+	ret := make([]string, 0, len(all))
+	for _, cmd := range all {
+		ret = append(ret, cmd.(string))
+	}
+	return ret
+}
+
+// expandHome 将路径开头的 ~ 替换为用户的主目录
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// isTerminal 文件是否为终端, 管道以及重定向的文件返回 false
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
-	_, _ = fmt.Fprintln(os.Stdout, ret)
+	return info.Mode()&os.ModeCharDevice != 0
 }
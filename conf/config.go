@@ -1,17 +1,29 @@
 package conf
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/AdeMQ/server/logger"
 	"github.com/AdeMQ/server/service"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 )
 
+// reloadInterval 检查配置文件是否发生变化的间隔
+const reloadInterval = 3 * time.Second
+
 var (
 	Conf     = &Config{}
 	confPath string
+	confFile string // 配置文件的绝对路径
+	content  []byte // 当前生效的配置文件内容
 )
 
 type Config struct {
@@ -25,7 +37,8 @@ type Server struct {
 }
 
 type Logger struct {
-	Stdout bool `yaml:"stdout" json:"stdout"`
+	Stdout bool   `yaml:"stdout" json:"stdout"`
+	Level  string `yaml:"level" json:"level"` // 日志级别 debug|info|warn|error, 为空时为 info, 重新加载配置时立即生效
 	File   *File
 }
 
@@ -59,11 +72,128 @@ func Init() (err error) {
 	if err != nil {
 		return
 	}
+	// 启动时与重新加载配置时使用相同的校验
+	if Conf.Server == nil {
+		return errors.New("缺少 server 配置")
+	}
+	if err = Conf.Server.Validate(); err != nil {
+		return
+	}
+	level, err := Conf.level()
+	if err != nil {
+		return
+	}
+	logger.SetLevel(level)
+	confFile, content = yamlFile, yamlRead
 	go load()
 	return
 }
 
-// 动态加载配置
+// level 配置的日志级别, 没有 logger 配置时为默认的级别
+func (c *Config) level() (logger.Level, error) {
+	if c.Logger == nil {
+		return logger.ParseLevel("")
+	}
+	return logger.ParseLevel(c.Logger.Level)
+}
+
+// fileStat 配置文件的修改时间与大小, 用于判断文件是否发生变化, 文件不存在时为零值
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile() fileStat {
+	fi, err := os.Stat(confFile)
+	if err != nil {
+		return fileStat{}
+	}
+	return fileStat{modTime: fi.ModTime(), size: fi.Size()}
+}
+
+// 动态加载配置: 收到 SIGHUP 信号或者配置文件发生变化时重新加载
+// 通过定时检查文件的修改时间与大小发现变化, 不依赖文件系统通知
 func load() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	last := statFile()   // 最后一次加载时的文件状态
+	var pending fileStat // 上一次检查发现变化时的文件状态
+	for {
+		select {
+		case <-hup:
+			// 更新文件状态, 避免之后的检查重复加载同一次修改
+			last, pending = statFile(), fileStat{}
+			logger.Info("Reload 收到 SIGHUP, 重新加载配置", confFile)
+			reload()
+		case <-ticker.C:
+			stat := statFile()
+			// 文件被删除或者被清空时不处理, 等待下一次检查
+			if stat == last || stat.size == 0 {
+				pending = fileStat{}
+				continue
+			}
+			// 文件可能正在写入, 连续两次检查的状态相同之后才加载
+			if stat != pending {
+				pending = stat
+				continue
+			}
+			last, pending = stat, fileStat{}
+			logger.Info("Reload 配置文件发生变化, 重新加载配置", confFile)
+			reload()
+		}
+	}
+}
 
+// reload 读取并校验配置文件, 校验通过之后更新运行中的服务, 失败时继续使用之前的配置
+func reload() {
+	yamlRead, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		logger.Error("Reload 读取配置文件失败", err.Error())
+		return
+	}
+	if bytes.Equal(yamlRead, content) {
+		logger.Info("Reload 配置没有变化")
+		return
+	}
+	next := &Config{}
+	if err = yaml.Unmarshal(yamlRead, next); err != nil {
+		logger.Error("Reload 配置文件格式错误, 继续使用之前的配置", err.Error())
+		return
+	}
+	if next.Server == nil {
+		logger.Error("Reload 缺少 server 配置, 继续使用之前的配置")
+		return
+	}
+	level, err := next.level()
+	if err != nil {
+		logger.Error("Reload 重新加载配置失败, 继续使用之前的配置", err.Error())
+		return
+	}
+	// 运行中的配置已经被补全了默认值, 重新解析之前的文件内容用于比较哪些配置发生了变化
+	prev := &Config{}
+	_ = yaml.Unmarshal(content, prev)
+	if prev.Server == nil {
+		prev.Server = &service.Config{}
+	}
+	applied, restart, err := service.Reload(prev.Server, next.Server)
+	if err != nil {
+		logger.Error("Reload 重新加载配置失败, 继续使用之前的配置", err.Error())
+		return
+	}
+	content = yamlRead
+	if level != logger.GetLevel() {
+		logger.SetLevel(level)
+		applied = append(applied, "logger.level")
+	}
+	if len(applied) > 0 {
+		logger.Info("Reload 已生效的配置", applied)
+	}
+	if len(restart) > 0 {
+		logger.Warn("Reload 以下配置需要重启服务才能生效", restart)
+	}
+	if len(applied) == 0 && len(restart) == 0 {
+		logger.Info("Reload 配置没有变化")
+	}
 }
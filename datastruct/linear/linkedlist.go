@@ -1,6 +1,14 @@
 package linear
 
+import (
+	"errors"
+)
+
+// LinkedList 双向链表
 type LinkedList struct {
+	head   *ListItem
+	tail   *ListItem
+	length int
 }
 
 type ListItem struct {
@@ -9,10 +17,75 @@ type ListItem struct {
 	Data interface{}
 }
 
-func (l *LinkedList) RPush() {
+// NewLinkedList 创建一个双向链表
+func NewLinkedList() *LinkedList {
+	return &LinkedList{}
+}
 
+// Length 获取链表长度
+func (l *LinkedList) Length() int {
+	return l.length
 }
 
-func (l *LinkedList) LPop() {
+// RPush 从尾部插入元素
+func (l *LinkedList) RPush(e interface{}) {
+	item := &ListItem{pre: l.tail, Data: e}
+	if l.tail == nil {
+		l.head = item
+	} else {
+		l.tail.next = item
+	}
+	l.tail = item
+	l.length++
+}
+
+// LPush 从头部插入元素
+func (l *LinkedList) LPush(e interface{}) {
+	item := &ListItem{next: l.head, Data: e}
+	if l.head == nil {
+		l.tail = item
+	} else {
+		l.head.pre = item
+	}
+	l.head = item
+	l.length++
+}
+
+// LPop 从头部弹出元素
+func (l *LinkedList) LPop() (interface{}, error) {
+	if l.head == nil {
+		return nil, errors.New("linked list is empty")
+	}
+	item := l.head
+	l.head = item.next
+	if l.head == nil {
+		l.tail = nil
+	} else {
+		l.head.pre = nil
+	}
+	item.next = nil
+	l.length--
+	return item.Data, nil
+}
+
+// FetchAllElem 获取所有内容
+func (l *LinkedList) FetchAllElem() []interface{} {
+	data := make([]interface{}, 0, l.length)
+	for item := l.head; item != nil; item = item.next {
+		data = append(data, item.Data)
+	}
+	return data
+}
 
+// Range 从头部第 start 个元素(从0开始)开始获取最多 n 个元素, 不会修改链表
+func (l *LinkedList) Range(start, n int) []interface{} {
+	var data []interface{}
+	i := 0
+	for item := l.head; item != nil && len(data) < n; item = item.next {
+		if i >= start {
+			data = append(data, item.Data)
+		}
+		i++
+	}
+	return data
 }
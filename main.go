@@ -3,8 +3,8 @@ package main
 import (
 	"flag"
 	"github.com/AdeMQ/conf"
+	"github.com/AdeMQ/server/logger"
 	"github.com/AdeMQ/server/service"
-	"log"
 )
 
 func main() {
@@ -13,8 +13,7 @@ func main() {
 		panic(err)
 	}
 
-	log.Println("Hello AdeMQ")
-	log.Println("TCP listen address ", conf.Conf.Server.Address)
+	logger.Info("Hello AdeMQ")
 
 	// 启动服务
 	_ = service.Run(conf.Conf.Server)
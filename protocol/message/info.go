@@ -0,0 +1,113 @@
+package message
+
+// InfoSections info 命令支持的分类, 不指定分类时返回全部
+var InfoSections = []string{"server", "config", "clients", "topics", "queues", "memory", "storage", "replication"}
+
+// Info 服务端运行状态, 只包含请求的分类, 其他分类为空
+type Info struct {
+	Server      *ServerInfo      `json:"server,omitempty"`
+	Config      *ConfigInfo      `json:"config,omitempty"`
+	Clients     *ClientsInfo     `json:"clients,omitempty"`
+	Topics      []TopicInfo      `json:"topics,omitempty"`
+	Queues      []QueueInfo      `json:"queues,omitempty"`
+	Memory      *MemoryInfo      `json:"memory,omitempty"`
+	Storage     *StorageInfo     `json:"storage,omitempty"`
+	Replication *ReplicationInfo `json:"replication,omitempty"`
+}
+
+// ServerInfo 服务端版本以及运行时长
+type ServerInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	PID       int    `json:"pid"`
+	StartTime int64  `json:"startTime"` // 启动时间, 单位 毫秒
+	Uptime    int64  `json:"uptime"`    // 运行时长, 单位 秒
+}
+
+// ConfigInfo 配置摘要, 不包含用户的密码等敏感信息
+type ConfigInfo struct {
+	Listeners  []ListenerInfo `json:"listeners"`
+	BufLen     int            `json:"bufLen"`
+	BufMaxLen  int            `json:"bufMaxLen"`
+	Auth       bool           `json:"auth"`
+	Users      int            `json:"users"`
+	ACLRules   int            `json:"aclRules"`
+	LimitMode  string         `json:"limitMode"`
+	LimitRates map[string]int `json:"limitRates"` // 各项限流速率, 0 表示不限制
+	Codecs     []string       `json:"codecs"`
+	Threshold  int            `json:"threshold"`
+	Retention  int            `json:"retention"`
+	AckTimeout int            `json:"ackTimeout"`
+}
+
+// ListenerInfo 监听配置摘要
+type ListenerInfo struct {
+	Network  string `json:"network"`
+	Address  string `json:"address"`
+	TLS      bool   `json:"tls"`
+	MaxConns int    `json:"maxConns"`
+}
+
+// ClientsInfo 客户端连接统计
+type ClientsInfo struct {
+	Connected     int   `json:"connected"`     // 当前的连接数
+	Total         int64 `json:"total"`         // 启动以来建立的连接总数
+	Subscriptions int   `json:"subscriptions"` // 所有主题的订阅数
+	Consumers     int   `json:"consumers"`     // 所有队列的推送消费者数
+}
+
+// TopicInfo 主题的消息统计, 速率为最近10秒的平均值, 单位 条/秒
+type TopicInfo struct {
+	Name        string  `json:"name"`
+	Messages    int     `json:"messages"` // 保留的消息条数
+	Bytes       int64   `json:"bytes"`    // 保留的消息内容字节数
+	FirstOffset int64   `json:"firstOffset"`
+	NextOffset  int64   `json:"nextOffset"`
+	Subscribers int     `json:"subscribers"`
+	Delayed     int     `json:"delayed"` // 等待到期投递的延迟消息条数
+	PublishRate float64 `json:"publishRate"`
+	DeliverRate float64 `json:"deliverRate"`
+}
+
+// QueueInfo 队列的消息统计, 速率为最近10秒的平均值, 单位 条/秒
+type QueueInfo struct {
+	Name        string  `json:"name"`
+	Ready       int     `json:"ready"`    // 等待投递的消息条数
+	Inflight    int     `json:"inflight"` // 已经投递等待确认的消息条数
+	Bytes       int64   `json:"bytes"`    // 等待投递与等待确认的消息内容字节数
+	Consumers   int     `json:"consumers"`
+	Pushed      int64   `json:"pushed"`
+	Acked       int64   `json:"acked"`
+	PushRate    float64 `json:"pushRate"`
+	DeliverRate float64 `json:"deliverRate"`
+	AckRate     float64 `json:"ackRate"`
+}
+
+// MemoryInfo 运行时的内存统计, 单位 字节
+type MemoryInfo struct {
+	HeapAlloc  uint64 `json:"heapAlloc"`
+	HeapInuse  uint64 `json:"heapInuse"`
+	HeapIdle   uint64 `json:"heapIdle"`
+	Sys        uint64 `json:"sys"`
+	NumGC      uint32 `json:"numGC"`
+	Goroutines int    `json:"goroutines"`
+	BufferPool int64  `json:"bufferPool"` // 读取缓冲池正在使用的字节数
+}
+
+// StorageInfo 消息存储统计
+type StorageInfo struct {
+	Engine     string `json:"engine"`     // 存储方式, 目前只有 memory
+	Persistent bool   `json:"persistent"` // 消息是否持久化, 内存存储重启之后消息丢失
+	Topics     int    `json:"topics"`
+	Queues     int    `json:"queues"`
+	Messages   int64  `json:"messages"` // 所有主题保留以及等待投递的延迟消息, 以及队列中的消息条数
+	Bytes      int64  `json:"bytes"`
+}
+
+// ReplicationInfo 复制状态
+type ReplicationInfo struct {
+	Role     string `json:"role"` // 目前只支持单机部署, 固定为 standalone
+	Replicas int    `json:"replicas"`
+}
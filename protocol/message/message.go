@@ -0,0 +1,119 @@
+package message
+
+import "encoding/json"
+
+// 响应状态码
+const (
+	CodeOK           = 0   // 成功
+	CodeBadRequest   = 400 // 请求格式或参数错误
+	CodeUnauthorized = 401 // 未认证或认证失败
+	CodeForbidden    = 403 // 没有操作权限
+	CodeNotFound     = 404 // 命令不存在
+	CodeLocked       = 423 // 账号因多次认证失败被锁定
+	CodeThrottled    = 429 // 超出限流配额, 需要稍后重试
+	CodeServerErr    = 500 // 服务端内部错误
+)
+
+// TypePush 服务端主动推送的消息类型
+const TypePush = "push"
+
+// Request 客户端请求结构
+type Request struct {
+	ID      uint64   `json:"id,omitempty"` // 请求编号, 服务端在响应中原样返回, 用于匹配请求与响应
+	Cmd     string   `json:"cmd"`
+	Params  []string `json:"params"`
+	Payload []byte   `json:"payload,omitempty"` // 消息内容, 二进制安全
+	Batch   [][]byte `json:"batch,omitempty"`   // 批量写入的多条消息内容
+	// Key 与 Headers 为写入消息时附带的消息键与消息头, 随消息原样投递给消费者
+	Key     string            `json:"key,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Delay   int64             `json:"delay,omitempty"` // 延迟投递的时间, 单位 毫秒, 由服务端保存到期之后再投递, 目前只用于 publish
+}
+
+// Response 服务端响应结构
+type Response struct {
+	ID   uint64      `json:"id,omitempty"`
+	Type string      `json:"type,omitempty"` // 为空表示请求的响应, push 表示服务端推送
+	Code int         `json:"code"`
+	Msg  string      `json:"msg,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Message 队列与主题中的消息
+type Message struct {
+	Topic     string            `json:"topic,omitempty"`
+	Queue     string            `json:"queue,omitempty"`
+	Offset    int64             `json:"offset"`       // 主题消息在主题内的偏移量
+	ID        uint64            `json:"id,omitempty"` // 队列消息的编号, 用于确认
+	Payload   []byte            `json:"payload"`
+	Timestamp int64             `json:"timestamp"`         // 写入时间, 单位 毫秒
+	Key       string            `json:"key,omitempty"`     // 消息键, 由生产者指定
+	Headers   map[string]string `json:"headers,omitempty"` // 消息头, 由生产者指定
+}
+
+// Hello 连接建立时协商的结果
+type Hello struct {
+	Codec     string `json:"codec"`     // 协商使用的压缩算法, 为空表示不压缩
+	Threshold int    `json:"threshold"` // 消息体超过该字节数才压缩
+}
+
+// Browsed peek 与 browse 命令返回的消息
+type Browsed struct {
+	Message
+	State    string `json:"state,omitempty"`    // 队列消息的状态 ready | inflight
+	Deadline int64  `json:"deadline,omitempty"` // 等待确认的消息的确认截止时间, 单位 毫秒
+}
+
+// Browse peek 与 browse 命令的结果, 查看消息不会改变消息的投递状态
+type Browse struct {
+	Messages []Browsed `json:"messages"`
+	Next     int64     `json:"next"` // 下一页的起始位置, 主题为偏移量, 队列为消息在队列中的位置
+	More     bool      `json:"more"` // 是否还有之后的消息
+}
+
+// OK 返回成功的响应
+func OK(data interface{}) *Response {
+	return &Response{Code: CodeOK, Data: data}
+}
+
+// Error 返回错误的响应
+func Error(code int, msg string) *Response {
+	return &Response{Code: code, Msg: msg}
+}
+
+// DecodeRequest 解析请求
+func DecodeRequest(b []byte) (*Request, error) {
+	req := &Request{}
+	if err := json.Unmarshal(b, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// DecodeResponse 解析响应
+func DecodeResponse(b []byte) (*Response, error) {
+	resp := &Response{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DecodeData 将响应的 Data 转换为具体的结构
+// 客户端解析响应时 Data 被解析为通用的 map 或者 slice, 需要重新序列化之后再转换
+func (r *Response) DecodeData(v interface{}) error {
+	b, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Encode 序列化响应
+func (r *Response) Encode() []byte {
+	b, err := json.Marshal(r)
+	if err != nil {
+		b, _ = json.Marshal(Error(CodeServerErr, err.Error()))
+	}
+	return b
+}
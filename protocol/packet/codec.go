@@ -0,0 +1,167 @@
+package packet
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// 消息头的4个字节中, 高4位用于保存消息体的压缩算法编号, 低28位保存消息体长度
+// 编号为0表示消息体未压缩, 因此未开启压缩的客户端发送的消息头与之前的格式完全兼容
+const (
+	ConstCodecShift = 28
+	ConstLenMask    = 1<<ConstCodecShift - 1
+	ConstDecodeErr  = "消息体解压失败"
+)
+
+// Codec 消息体压缩算法
+type Codec interface {
+	ID() byte     // 编号, 取值 1-15, 写入消息头
+	Name() string // 名称, 用于连接时协商
+	Encode(src []byte) ([]byte, error)
+	Decode(src []byte) (io.ReadCloser, error)
+}
+
+var (
+	codecMu     sync.RWMutex
+	codecByID   = make(map[byte]Codec)
+	codecByName = make(map[string]Codec)
+)
+
+func init() {
+	RegisterCodec(flateCodec{})
+	RegisterCodec(gzipCodec{})
+}
+
+// RegisterCodec 注册压缩算法, 编号或者名称重复时覆盖之前的注册
+func RegisterCodec(c Codec) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	codecByID[c.ID()] = c
+	codecByName[c.Name()] = c
+}
+
+// CodecByName 根据名称获取压缩算法, 不存在时返回 nil
+func CodecByName(name string) Codec {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	return codecByName[name]
+}
+
+// HeadCodecID 从消息头中提取压缩算法编号
+func HeadCodecID(head []byte) byte {
+	return head[0] >> (ConstCodecShift - 24)
+}
+
+// Compress 按照阈值压缩消息体, 返回压缩算法编号以及需要发送的内容
+// 消息体小于阈值或者压缩之后没有变小时, 原样返回并且编号为0
+func Compress(c Codec, threshold int, content []byte) (byte, []byte) {
+	if c == nil || len(content) < threshold {
+		return 0, content
+	}
+	encoded, err := c.Encode(content)
+	if err != nil || len(encoded) >= len(content) {
+		return 0, content
+	}
+	return c.ID(), encoded
+}
+
+// Decompress 根据消息头中的编号解压消息体, 解压之后的长度不能超过 limit, 避免压缩炸弹
+func Decompress(id byte, content []byte, limit int) ([]byte, error) {
+	if id == 0 {
+		return content, nil
+	}
+	codecMu.RLock()
+	c, ok := codecByID[id]
+	codecMu.RUnlock()
+	if !ok {
+		return nil, errors.New(ConstDecodeErr)
+	}
+	rc, err := c.Decode(content)
+	if err != nil {
+		return nil, errors.New(ConstDecodeErr)
+	}
+	defer rc.Close()
+	decoded, err := ioutil.ReadAll(io.LimitReader(rc, int64(limit)+1))
+	if err != nil {
+		return nil, errors.New(ConstDecodeErr)
+	}
+	if len(decoded) > limit {
+		return nil, errors.New(ConstBufferFullErr)
+	}
+	return decoded, nil
+}
+
+// flateCodec 使用 compress/flate 压缩
+type flateCodec struct{}
+
+func (flateCodec) ID() byte     { return 1 }
+func (flateCodec) Name() string { return "flate" }
+
+func (flateCodec) Encode(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(src); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (flateCodec) Decode(src []byte) (io.ReadCloser, error) {
+	return flate.NewReader(bytes.NewReader(src)), nil
+}
+
+// gzipCodec 使用 compress/gzip 压缩
+type gzipCodec struct{}
+
+func (gzipCodec) ID() byte     { return 2 }
+func (gzipCodec) Name() string { return "gzip" }
+
+func (gzipCodec) Encode(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(src []byte) (io.ReadCloser, error) {
+	return gzip.NewReader(bytes.NewReader(src))
+}
+
+// CompressConfig 压缩配置
+type CompressConfig struct {
+	Codecs    []string `yaml:"codecs" json:"codecs"`       // 允许使用的压缩算法, 为空表示不开启压缩
+	Threshold int      `yaml:"threshold" json:"threshold"` // 消息体超过该字节数才压缩
+}
+
+// Negotiate 从客户端支持的压缩算法列表中按照客户端的偏好顺序选出双方都支持的算法, 没有时返回 nil
+func (c *CompressConfig) Negotiate(names []string) Codec {
+	if c == nil {
+		return nil
+	}
+	for _, name := range names {
+		for _, allowed := range c.Codecs {
+			if name == allowed {
+				if codec := CodecByName(name); codec != nil {
+					return codec
+				}
+			}
+		}
+	}
+	return nil
+}
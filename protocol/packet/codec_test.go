@@ -0,0 +1,34 @@
+package packet
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	content := bytes.Repeat([]byte(`{"cmd":"publish","params":["orders"]}`), 100)
+	for _, name := range []string{"flate", "gzip"} {
+		codec := CodecByName(name)
+		id, encoded := Compress(codec, 1024, content)
+		if id != codec.ID() || len(encoded) >= len(content) {
+			t.Fatalf("%s: content not compressed", name)
+		}
+		head := make([]byte, ConstHeadSize)
+		head[0] = id << (ConstCodecShift - 24)
+		decoded, err := Decompress(HeadCodecID(head), encoded, len(content))
+		if err != nil || !bytes.Equal(decoded, content) {
+			t.Fatalf("%s: round trip failed: %v", name, err)
+		}
+		// 解压之后超过上限的消息需要拒绝
+		if _, err = Decompress(id, encoded, len(content)-1); err == nil {
+			t.Fatalf("%s: decompress limit not applied", name)
+		}
+	}
+}
+
+func TestCompressBelowThreshold(t *testing.T) {
+	content := []byte("ping")
+	if id, ret := Compress(CodecByName("gzip"), 1024, content); id != 0 || !bytes.Equal(ret, content) {
+		t.Fatal("content below threshold should not be compressed")
+	}
+}
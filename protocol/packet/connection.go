@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"net"
+	"sync/atomic"
 )
 
 const (
@@ -15,12 +16,15 @@ type ReadableEventChan chan []byte
 type WritableEventChan chan []byte
 
 type TcpConn struct {
-	Conn          net.Conn
-	readBuf       []byte
-	readStart     int
-	readEnd       int
-	maxReadBufLen int
-	Closed        bool
+	Conn           net.Conn
+	pool           *BufferPool
+	baseReadBufLen int // 读取缓冲区的初始长度, 处理完大消息之后缓冲区会收缩回该长度
+	readBuf        []byte
+	readStart      int
+	readEnd        int
+	maxReadBufLen  int
+	codec          atomic.Value // 发送消息时使用的 codecSetting, 在读取协程中协商确定, 同时被推送协程读取
+	Closed         bool
 	ReadableEventChan
 	WritableEventChan
 }
@@ -34,15 +38,20 @@ func New(conn net.Conn, readBufLen int, maxReadBufLen int) *TcpConn {
 	if maxReadBufLen == 0 {
 		maxReadBufLen = 1024 * 1024 * 10
 	}
-	readBuf := make([]byte, readBufLen)
+	// 读取缓冲区从共享的缓冲池中获取, 连接关闭时归还
+	readBuf := DefaultPool.Get(readBufLen)
 	readChan := make(chan []byte, 10)
-	writeChan := make(chan []byte, 10)
+	// 发送通道同时用于服务端推送, 需要容纳一定量的突发消息
+	writeChan := make(chan []byte, 1024)
 	return &TcpConn{
 		conn,
+		DefaultPool,
+		len(readBuf),
 		readBuf,
 		0,
 		0,
 		maxReadBufLen,
+		atomic.Value{},
 		false,
 		readChan,
 		writeChan,
@@ -51,12 +60,17 @@ func New(conn net.Conn, readBufLen int, maxReadBufLen int) *TcpConn {
 
 func (tc *TcpConn) Close() {
 	tc.Closed = true
+	if tc.readBuf != nil {
+		tc.pool.Put(tc.readBuf)
+		tc.readBuf = nil
+	}
 	defer close(tc.WritableEventChan)
 	defer close(tc.ReadableEventChan)
 }
 
 // ReadFromConn 从conn里面读取数据，conn可能阻塞
 func (tc *TcpConn) ReadFromConn() (int, error) {
+	tc.readBufShrink()
 	tc.readBufLeftShift()
 	// 在缓冲区不能承载整个消息体的时候，我们需要对缓冲区扩容, 或者直接抛出异常
 	if tc.readEnd >= len(tc.readBuf) {
@@ -64,9 +78,11 @@ func (tc *TcpConn) ReadFromConn() (int, error) {
 		if tc.readEnd >= tc.maxReadBufLen {
 			return 0, errors.New(ConstBufferFullErr)
 		}
-		newBuf := make([]byte, len(tc.readBuf)*2)
+		newBuf := tc.pool.Get(len(tc.readBuf) * 2)
 		copy(newBuf, tc.readBuf)
+		tc.pool.Put(tc.readBuf)
 		tc.readBuf = newBuf
+		atomic.AddUint64(&tc.pool.grows, 1)
 	}
 	// 此处如果传入读取的缓冲区空闲长度为0，会陷入死循环， 所以前面做了扩容以及异常处理
 	n, err := tc.Conn.Read(tc.readBuf[tc.readEnd:])
@@ -77,6 +93,20 @@ func (tc *TcpConn) ReadFromConn() (int, error) {
 	return n, nil
 }
 
+// readBufShrink 大消息处理完之后, 将扩容过的读取缓冲区归还到缓冲池, 换回初始长度的缓冲区
+// 剩余内容不超过初始长度的一半时才收缩, 避免连续的大消息导致缓冲区反复扩容与收缩
+func (tc *TcpConn) readBufShrink() {
+	if len(tc.readBuf) <= tc.baseReadBufLen || tc.ReadBufLen() > tc.baseReadBufLen/2 {
+		return
+	}
+	newBuf := tc.pool.Get(tc.baseReadBufLen)
+	tc.readEnd = copy(newBuf, tc.readBuf[tc.readStart:tc.readEnd])
+	tc.readStart = 0
+	tc.pool.Put(tc.readBuf)
+	tc.readBuf = newBuf
+	atomic.AddUint64(&tc.pool.shrinks, 1)
+}
+
 // leftShift 将读取缓冲区的有用字节前移
 func (tc *TcpConn) readBufLeftShift() {
 	if tc.readStart == 0 {
@@ -111,6 +141,22 @@ func (tc *TcpConn) Read(offset, n int) []byte {
 	return buf
 }
 
+// codecSetting 压缩算法以及压缩阈值
+type codecSetting struct {
+	codec  Codec // 为 nil 表示不压缩
+	minLen int   // 消息体超过该长度才会压缩
+}
+
+// SetCodec 设置发送消息时使用的压缩算法以及压缩阈值, codec 为 nil 表示不压缩
+func (tc *TcpConn) SetCodec(codec Codec, minLen int) {
+	tc.codec.Store(codecSetting{codec: codec, minLen: minLen})
+}
+
+// Decompress 根据消息头解压消息体
+func (tc *TcpConn) Decompress(head, content []byte) ([]byte, error) {
+	return Decompress(HeadCodecID(head), content, tc.maxReadBufLen)
+}
+
 // SendMessageToChan 向连接发送消息
 func (tc *TcpConn) SendMessageToChan(content []byte) error {
 	if tc.Closed {
@@ -123,8 +169,11 @@ func (tc *TcpConn) SendMessageToChan(content []byte) error {
 // SendMessageDirect 向连接发送消息
 func (tc *TcpConn) SendMessageDirect(content []byte) error {
 	headBytes := make([]byte, ConstHeadSize)
+	setting, _ := tc.codec.Load().(codecSetting)
+	codecID, content := Compress(setting.codec, setting.minLen, content)
 	contentSize := len(content)
 	headBytes = tc.IntToBytes(contentSize)
+	headBytes[0] |= codecID << (ConstCodecShift - 24)
 	_, err := tc.Conn.Write(append(headBytes, content...))
 	if err != nil {
 		return err
@@ -142,6 +191,6 @@ func (tc *TcpConn) IntToBytes(n int) []byte {
 
 // BytesToInt 字节转换成整型
 func (tc *TcpConn) BytesToInt(b []byte) int {
-	// (32位下如果超出整型上限可能有错误)
-	return int(binary.BigEndian.Uint32(b))
+	// 高4位为压缩算法编号, 不计入长度
+	return int(binary.BigEndian.Uint32(b) & ConstLenMask)
 }
@@ -0,0 +1,106 @@
+package packet
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// 缓冲池的尺寸等级从 1k 开始按照2的幂增长, 最大到 16M, 超过最大等级的缓冲区不做复用
+const (
+	ConstPoolMinSize = 1024
+	ConstPoolMaxSize = 1024 * 1024 * 16
+)
+
+// DefaultPool 所有连接共享的读取缓冲池
+var DefaultPool = NewBufferPool(ConstPoolMinSize, ConstPoolMaxSize)
+
+// BufferPool 按照尺寸等级划分的缓冲池
+type BufferPool struct {
+	sizes []int
+	pools []sync.Pool
+	gets  []uint64 // 各个等级的获取次数
+
+	allocs   uint64 // 池中没有可用缓冲区时新分配的次数
+	oversize uint64 // 超出最大等级直接分配的次数
+	puts     uint64 // 归还次数
+	grows    uint64 // 连接缓冲区扩容次数
+	shrinks  uint64 // 连接缓冲区收缩次数
+	inUse    int64  // 已经取出尚未归还的字节数
+}
+
+// PoolStats 缓冲池统计信息
+type PoolStats struct {
+	Classes    map[int]uint64 `json:"classes"` // 尺寸等级 => 获取次数
+	Allocs     uint64         `json:"allocs"`
+	Oversize   uint64         `json:"oversize"`
+	Puts       uint64         `json:"puts"`
+	Grows      uint64         `json:"grows"`
+	Shrinks    uint64         `json:"shrinks"`
+	InUseBytes int64          `json:"inUseBytes"`
+}
+
+// NewBufferPool 创建缓冲池, minSize 与 maxSize 需要是2的幂
+func NewBufferPool(minSize, maxSize int) *BufferPool {
+	p := &BufferPool{}
+	for size := minSize; size <= maxSize; size *= 2 {
+		p.sizes = append(p.sizes, size)
+	}
+	p.pools = make([]sync.Pool, len(p.sizes))
+	p.gets = make([]uint64, len(p.sizes))
+	return p
+}
+
+// class 获取能够容纳 size 字节的最小等级, 超出最大等级时返回 -1
+func (p *BufferPool) class(size int) int {
+	for i, s := range p.sizes {
+		if size <= s {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get 获取容量至少为 size 的缓冲区, 返回的缓冲区长度为所在等级的尺寸
+func (p *BufferPool) Get(size int) []byte {
+	idx := p.class(size)
+	if idx < 0 {
+		atomic.AddUint64(&p.oversize, 1)
+		atomic.AddInt64(&p.inUse, int64(size))
+		return make([]byte, size)
+	}
+	atomic.AddUint64(&p.gets[idx], 1)
+	atomic.AddInt64(&p.inUse, int64(p.sizes[idx]))
+	if b, ok := p.pools[idx].Get().(*[]byte); ok {
+		return *b
+	}
+	atomic.AddUint64(&p.allocs, 1)
+	return make([]byte, p.sizes[idx])
+}
+
+// Put 归还缓冲区, 只有长度恰好为某个等级尺寸的缓冲区才会被复用
+func (p *BufferPool) Put(b []byte) {
+	atomic.AddUint64(&p.puts, 1)
+	atomic.AddInt64(&p.inUse, -int64(len(b)))
+	idx := p.class(len(b))
+	if idx < 0 || p.sizes[idx] != len(b) {
+		return
+	}
+	p.pools[idx].Put(&b)
+}
+
+// Stats 获取缓冲池统计信息
+func (p *BufferPool) Stats() PoolStats {
+	stats := PoolStats{
+		Classes:    make(map[int]uint64, len(p.sizes)),
+		Allocs:     atomic.LoadUint64(&p.allocs),
+		Oversize:   atomic.LoadUint64(&p.oversize),
+		Puts:       atomic.LoadUint64(&p.puts),
+		Grows:      atomic.LoadUint64(&p.grows),
+		Shrinks:    atomic.LoadUint64(&p.shrinks),
+		InUseBytes: atomic.LoadInt64(&p.inUse),
+	}
+	for i, size := range p.sizes {
+		stats.Classes[size] = atomic.LoadUint64(&p.gets[i])
+	}
+	return stats
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"path"
+)
+
+// Perm 权限类型
+type Perm string
+
+const (
+	PermPublish Perm = "publish" // 向 topic/queue 写入消息
+	PermConsume Perm = "consume" // 从 topic/queue 读取消息
+	PermAdmin   Perm = "admin"   // 管理权限, 包含其他所有权限
+)
+
+// 受保护的资源类型
+const (
+	ResourceTopic = "topic"
+	ResourceQueue = "queue"
+)
+
+// Rule 访问控制规则
+type Rule struct {
+	User     string `yaml:"user" json:"user"`         // 用户名, * 表示所有用户
+	Resource string `yaml:"resource" json:"resource"` // 资源类型 topic|queue, * 表示所有类型
+	// Name 资源名称, 支持精确匹配以及通配符, 例如 orders 、 orders.* 、 *
+	Name  string `yaml:"name" json:"name"`
+	Perms []Perm `yaml:"perms" json:"perms"`
+}
+
+// match 判断规则是否适用于给定的用户与资源
+func (r *Rule) match(user, resource, name string) bool {
+	if r.User != "*" && r.User != user {
+		return false
+	}
+	if r.Resource != "*" && r.Resource != resource {
+		return false
+	}
+	ok, err := path.Match(r.Name, name)
+	return err == nil && ok
+}
+
+// grant 判断规则是否授予了给定权限
+func (r *Rule) grant(perm Perm) bool {
+	for _, p := range r.Perms {
+		if p == perm || p == PermAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Allow 判断用户是否拥有对资源的操作权限
+// 未开启认证或者未配置任何规则时不做限制; 配置了规则之后, 只有被规则显式授权的操作才会被允许
+// resource 为空表示与具体资源无关的操作, 只有 resource 为 * 的规则对其生效
+func (a *Authenticator) Allow(user, resource, name string, perm Perm) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if !a.conf.Enable || len(a.conf.ACL) == 0 {
+		return true
+	}
+	for _, rule := range a.conf.ACL {
+		if rule.match(user, resource, name) && rule.grant(perm) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package auth
+
+import "testing"
+
+func TestAllow(t *testing.T) {
+	a := New(&Config{
+		Enable: true,
+		ACL: []*Rule{
+			{User: "admin", Resource: "*", Name: "*", Perms: []Perm{PermAdmin}},
+			{User: "*", Resource: ResourceTopic, Name: "orders.*", Perms: []Perm{PermConsume}},
+			{User: "producer", Resource: ResourceTopic, Name: "orders.*", Perms: []Perm{PermPublish}},
+			{User: "producer", Resource: ResourceQueue, Name: "jobs", Perms: []Perm{PermPublish}},
+		},
+	})
+	cases := []struct {
+		user, resource, name string
+		perm                 Perm
+		want                 bool
+	}{
+		{"admin", ResourceQueue, "anything", PermPublish, true},
+		{"admin", "", "", PermAdmin, true},
+		{"guest", ResourceTopic, "orders.created", PermConsume, true},
+		{"guest", ResourceTopic, "orders.created", PermPublish, false},
+		{"guest", ResourceTopic, "payments", PermConsume, false},
+		{"producer", ResourceTopic, "orders.created", PermPublish, true},
+		{"producer", ResourceQueue, "jobs", PermPublish, true},
+		{"producer", ResourceQueue, "jobs.retry", PermPublish, false},
+		{"producer", ResourceQueue, "jobs", PermConsume, false},
+		{"producer", "", "", PermAdmin, false},
+	}
+	for _, c := range cases {
+		if got := a.Allow(c.user, c.resource, c.name, c.perm); got != c.want {
+			t.Errorf("Allow(%s, %s, %s, %s) = %v, want %v", c.user, c.resource, c.name, c.perm, got, c.want)
+		}
+	}
+}
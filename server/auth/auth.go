@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBadCredentials = errors.New("用户名或密码错误")
+	ErrLocked         = errors.New("认证失败次数过多, 账号已被锁定, 请稍后再试")
+)
+
+type Config struct {
+	Enable      bool    `yaml:"enable" json:"enable"`
+	MaxFailures int     `yaml:"maxFailures" json:"maxFailures"`
+	LockSeconds int     `yaml:"lockSeconds" json:"lockSeconds"`
+	Users       []*User `yaml:"users" json:"users"`
+	ACL         []*Rule `yaml:"acl" json:"acl"`
+}
+
+// User 用户信息, 密码以 hex(sha256(salt + password)) 的形式保存
+type User struct {
+	Name string `yaml:"name" json:"name"`
+	Salt string `yaml:"salt" json:"salt"`
+	Hash string `yaml:"hash" json:"-"`
+}
+
+// maxFailureEntries 认证失败记录的数量上限, 超出时先清理过期的记录, 避免尝试大量用户名导致记录无限增长
+const maxFailureEntries = 10000
+
+// failure 用户认证失败记录
+type failure struct {
+	count       int
+	last        time.Time // 最近一次失败的时间
+	lockedUntil time.Time
+}
+
+// expired 记录是否已经失效: 不在锁定期内, 并且最近一次失败已经超过锁定时长
+func (f *failure) expired(now time.Time, lock time.Duration) bool {
+	return !now.Before(f.lockedUntil) && now.Sub(f.last) >= lock
+}
+
+// Authenticator 用户认证器, 配置可以通过 Update 在运行中更新
+type Authenticator struct {
+	mu       sync.RWMutex
+	conf     *Config
+	users    map[string]*User
+	failures map[string]*failure // 用户名与客户端地址 => 失败记录
+}
+
+// New 根据配置创建认证器, conf 为 nil 时表示不开启认证
+func New(conf *Config) *Authenticator {
+	a := &Authenticator{failures: make(map[string]*failure)}
+	a.Update(conf)
+	return a
+}
+
+// Update 更新认证配置, 用户列表与访问控制规则立即生效, 已经通过认证的连接不需要重新认证
+// 用户的认证失败记录以及锁定状态会保留
+func (a *Authenticator) Update(conf *Config) {
+	if conf == nil {
+		conf = &Config{}
+	}
+	// 默认连续失败5次锁定5分钟
+	if conf.MaxFailures <= 0 {
+		conf.MaxFailures = 5
+	}
+	if conf.LockSeconds <= 0 {
+		conf.LockSeconds = 300
+	}
+	users := make(map[string]*User, len(conf.Users))
+	for _, u := range conf.Users {
+		users[u.Name] = u
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.conf = conf
+	a.users = users
+}
+
+// Enabled 是否开启认证
+func (a *Authenticator) Enabled() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.conf.Enable
+}
+
+// Verify 校验用户名与密码, host 为客户端地址(不含端口)
+// 同一个客户端地址对同一个用户连续失败达到 MaxFailures 次之后, 该地址在 LockSeconds 秒内对该用户的认证请求都会直接返回 ErrLocked
+// 锁定按照用户名与客户端地址区分, 其他地址的错误尝试不会锁定正常用户
+func (a *Authenticator) Verify(name, password, host string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	lock := time.Duration(a.conf.LockSeconds) * time.Second
+	key := name + "@" + host
+	f, ok := a.failures[key]
+	if ok && now.Before(f.lockedUntil) {
+		return ErrLocked
+	}
+	u, exists := a.users[name]
+	if exists && subtle.ConstantTimeCompare([]byte(HashPassword(u.Salt, password)), []byte(u.Hash)) == 1 {
+		delete(a.failures, key)
+		return nil
+	}
+	// 不存在的用户同样计数, 避免通过锁定行为探测用户是否存在
+	if ok && f.expired(now, lock) {
+		f.count = 0
+	}
+	if !ok {
+		if len(a.failures) >= maxFailureEntries {
+			a.sweep(now, lock)
+		}
+		f = &failure{}
+		a.failures[key] = f
+	}
+	f.count++
+	f.last = now
+	if f.count >= a.conf.MaxFailures {
+		f.count = 0
+		f.lockedUntil = now.Add(lock)
+		return ErrLocked
+	}
+	return ErrBadCredentials
+}
+
+// sweep 清理已经失效的失败记录, 仍然超出上限时再清理未处于锁定期的记录,
+// 全部处于锁定期时按照解除锁定的时间清理最早的一成记录, 避免每次新增记录都需要排序, 调用时需要持有锁
+func (a *Authenticator) sweep(now time.Time, lock time.Duration) {
+	for key, f := range a.failures {
+		if f.expired(now, lock) {
+			delete(a.failures, key)
+		}
+	}
+	for key, f := range a.failures {
+		if len(a.failures) < maxFailureEntries {
+			return
+		}
+		if !now.Before(f.lockedUntil) {
+			delete(a.failures, key)
+		}
+	}
+	if len(a.failures) < maxFailureEntries {
+		return
+	}
+	locked := make([]string, 0, len(a.failures))
+	for key := range a.failures {
+		locked = append(locked, key)
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		return a.failures[locked[i]].lockedUntil.Before(a.failures[locked[j]].lockedUntil)
+	})
+	for _, key := range locked[:len(locked)-maxFailureEntries*9/10] {
+		delete(a.failures, key)
+	}
+}
+
+// HashPassword 计算加盐之后的密码摘要
+func HashPassword(salt, password string) string {
+	sum := sha256.Sum256([]byte(salt + password))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyLockout(t *testing.T) {
+	a := New(&Config{
+		Enable:      true,
+		MaxFailures: 3,
+		Users:       []*User{{Name: "alice", Salt: "s", Hash: HashPassword("s", "pw")}},
+	})
+	for i := 0; i < 2; i++ {
+		if err := a.Verify("alice", "bad", "10.0.0.1"); err != ErrBadCredentials {
+			t.Fatalf("attempt %d: err = %v, want %v", i, err, ErrBadCredentials)
+		}
+	}
+	if err := a.Verify("alice", "bad", "10.0.0.1"); err != ErrLocked {
+		t.Fatalf("err = %v, want %v", err, ErrLocked)
+	}
+	if err := a.Verify("alice", "pw", "10.0.0.1"); err != ErrLocked {
+		t.Errorf("locked host: err = %v, want %v", err, ErrLocked)
+	}
+	// 其他地址不受影响
+	if err := a.Verify("alice", "pw", "10.0.0.2"); err != nil {
+		t.Errorf("other host: err = %v, want nil", err)
+	}
+}
+
+func TestVerifyFailureEntriesBounded(t *testing.T) {
+	a := New(&Config{Enable: true})
+	for i := 0; i < maxFailureEntries+100; i++ {
+		_ = a.Verify("user"+strconv.Itoa(i), "bad", "10.0.0.1")
+	}
+	if n := len(a.failures); n > maxFailureEntries {
+		t.Errorf("failures = %d, want <= %d", n, maxFailureEntries)
+	}
+}
+
+func TestVerifyLockedEntriesBounded(t *testing.T) {
+	a := New(&Config{Enable: true, MaxFailures: 1})
+	// 每次失败都会锁定, 记录全部处于锁定期
+	for i := 0; i < maxFailureEntries; i++ {
+		if err := a.Verify("user"+strconv.Itoa(i), "bad", "10.0.0.1"); err != ErrLocked {
+			t.Fatalf("attempt %d: err = %v, want %v", i, err, ErrLocked)
+		}
+	}
+	// 最早的记录解除锁定的时间最早, 需要优先清理
+	a.failures["user0@10.0.0.1"].lockedUntil = time.Now().Add(time.Second)
+	for i := maxFailureEntries; i < maxFailureEntries+100; i++ {
+		_ = a.Verify("user"+strconv.Itoa(i), "bad", "10.0.0.1")
+	}
+	if n := len(a.failures); n > maxFailureEntries {
+		t.Errorf("failures = %d, want <= %d", n, maxFailureEntries)
+	}
+	if _, ok := a.failures["user0@10.0.0.1"]; ok {
+		t.Error("entry closest to unlock was not evicted")
+	}
+	if err := a.Verify("user"+strconv.Itoa(maxFailureEntries+99), "bad", "10.0.0.1"); err != ErrLocked {
+		t.Errorf("newest entry: err = %v, want %v", err, ErrLocked)
+	}
+}
@@ -0,0 +1,191 @@
+package broker
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Retention 每个主题最多保留的消息条数, 超出之后丢弃最早的消息
+	Retention int `yaml:"retention" json:"retention"`
+	// AckTimeout 队列消息被取出之后等待确认的时长, 单位 秒, 超时未确认的消息会重新投递
+	AckTimeout int `yaml:"ackTimeout" json:"ackTimeout"`
+}
+
+// Broker 管理所有的主题与队列, 主题与队列在第一次使用时自动创建
+type Broker struct {
+	conf   *Config
+	mu     sync.RWMutex
+	topics map[string]*Topic
+	queues map[string]*Queue
+}
+
+// New 创建 Broker, 并开启超时未确认消息的重新投递以及消息速率采样的协程
+func New(conf *Config) *Broker {
+	b := &Broker{
+		conf:   withDefaults(conf),
+		topics: make(map[string]*Topic),
+		queues: make(map[string]*Queue),
+	}
+	go b.tickLoop()
+	return b
+}
+
+func withDefaults(conf *Config) *Config {
+	if conf == nil {
+		conf = &Config{}
+	}
+	if conf.Retention <= 0 {
+		conf.Retention = 10000
+	}
+	if conf.AckTimeout <= 0 {
+		conf.AckTimeout = 30
+	}
+	return conf
+}
+
+// Update 更新配置, 保留条数立即对所有主题生效, 超出的旧消息会被丢弃
+// 确认超时时间对之后取出的队列消息生效, 已经取出的消息仍然使用取出时的截止时间
+func (b *Broker) Update(conf *Config) {
+	conf = withDefaults(conf)
+	b.mu.Lock()
+	b.conf = conf
+	b.mu.Unlock()
+	topics, queues := b.all()
+	for _, t := range topics {
+		t.setRetention(conf.Retention)
+	}
+	for _, q := range queues {
+		q.setAckTimeout(time.Duration(conf.AckTimeout) * time.Second)
+	}
+}
+
+// Topic 获取主题, 不存在时创建
+func (b *Broker) Topic(name string) *Topic {
+	b.mu.RLock()
+	t, ok := b.topics[name]
+	b.mu.RUnlock()
+	if ok {
+		return t
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok = b.topics[name]; !ok {
+		t = newTopic(name, b.conf.Retention)
+		b.topics[name] = t
+	}
+	return t
+}
+
+// Queue 获取队列, 不存在时创建
+func (b *Broker) Queue(name string) *Queue {
+	b.mu.RLock()
+	q, ok := b.queues[name]
+	b.mu.RUnlock()
+	if ok {
+		return q
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if q, ok = b.queues[name]; !ok {
+		q = newQueue(name, time.Duration(b.conf.AckTimeout)*time.Second)
+		b.queues[name] = q
+	}
+	return q
+}
+
+// FindTopic 获取已经存在的主题, 不会创建主题
+func (b *Broker) FindTopic(name string) (*Topic, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	t, ok := b.topics[name]
+	return t, ok
+}
+
+// FindQueue 获取已经存在的队列, 不会创建队列
+func (b *Broker) FindQueue(name string) (*Queue, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	q, ok := b.queues[name]
+	return q, ok
+}
+
+// TopicNames 获取所有主题的名称
+func (b *Broker) TopicNames() []string {
+	b.mu.RLock()
+	names := make([]string, 0, len(b.topics))
+	for name := range b.topics {
+		names = append(names, name)
+	}
+	b.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// QueueNames 获取所有队列的名称
+func (b *Broker) QueueNames() []string {
+	b.mu.RLock()
+	names := make([]string, 0, len(b.queues))
+	for name := range b.queues {
+		names = append(names, name)
+	}
+	b.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Config 生效的配置, 未配置的项为默认值
+func (b *Broker) Config() Config {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return *b.conf
+}
+
+// Info 所有主题与队列的消息统计, 按照名称排序
+func (b *Broker) Info() ([]message.TopicInfo, []message.QueueInfo) {
+	topics, queues := b.all()
+	topicInfos := make([]message.TopicInfo, 0, len(topics))
+	for _, t := range topics {
+		topicInfos = append(topicInfos, t.Info())
+	}
+	queueInfos := make([]message.QueueInfo, 0, len(queues))
+	for _, q := range queues {
+		queueInfos = append(queueInfos, q.Info())
+	}
+	sort.Slice(topicInfos, func(i, j int) bool { return topicInfos[i].Name < topicInfos[j].Name })
+	sort.Slice(queueInfos, func(i, j int) bool { return queueInfos[i].Name < queueInfos[j].Name })
+	return topicInfos, queueInfos
+}
+
+// all 获取所有主题与队列, 之后的操作不持有 Broker 的锁
+func (b *Broker) all() ([]*Topic, []*Queue) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	topics := make([]*Topic, 0, len(b.topics))
+	for _, t := range b.topics {
+		topics = append(topics, t)
+	}
+	queues := make([]*Queue, 0, len(b.queues))
+	for _, q := range b.queues {
+		queues = append(queues, q)
+	}
+	return topics, queues
+}
+
+// tickLoop 每秒检查一次所有队列中超时未确认的消息, 并采样主题与队列的消息速率
+func (b *Broker) tickLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		topics, queues := b.all()
+		for _, t := range topics {
+			t.tick()
+		}
+		for _, q := range queues {
+			q.requeueExpired(now)
+			q.tick()
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package broker
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"testing"
+	"time"
+)
+
+// recorder 记录收到的消息, closed 为 true 时模拟已经断开的订阅者
+type recorder struct {
+	msgs   []*message.Message
+	closed bool
+}
+
+func (r *recorder) Deliver(msg *message.Message) bool {
+	if r.closed {
+		return false
+	}
+	r.msgs = append(r.msgs, msg)
+	return true
+}
+
+func TestTopicSubscribeReplay(t *testing.T) {
+	topic := newTopic("t", 1000)
+	for i := 0; i < replayBatch*2+10; i++ {
+		topic.Publish([]byte("x"))
+	}
+	r := &recorder{}
+	if !topic.Subscribe(r, 5) {
+		t.Fatal("订阅失败")
+	}
+	if len(r.msgs) != replayBatch*2+5 || r.msgs[0].Offset != 5 {
+		t.Fatalf("补发 %d 条消息", len(r.msgs))
+	}
+	for i, msg := range r.msgs {
+		if msg.Offset != int64(i+5) {
+			t.Fatalf("第 %d 条消息的偏移量为 %d", i, msg.Offset)
+		}
+	}
+	topic.Publish([]byte("y"))
+	if last := r.msgs[len(r.msgs)-1]; last.Offset != replayBatch*2+10 {
+		t.Fatalf("订阅之后的消息偏移量为 %d", last.Offset)
+	}
+
+	if topic.Subscribe(&recorder{closed: true}, 0) {
+		t.Fatal("补发失败时不能注册订阅")
+	}
+}
+
+func TestQueueRequeueOnFailedDeliver(t *testing.T) {
+	q := newQueue("q", time.Minute)
+	closed := &recorder{closed: true}
+	q.Consume(closed, 10)
+	msg := q.Push([]byte("x"))
+	if q.Info().Ready != 1 || q.Info().Inflight != 0 || q.Info().Consumers != 0 {
+		t.Fatalf("推送失败的消息需要放回队列: %+v", q.Info())
+	}
+	r := &recorder{}
+	q.Consume(r, 10)
+	if len(r.msgs) != 1 || r.msgs[0].ID != msg.ID {
+		t.Fatalf("收到 %d 条消息", len(r.msgs))
+	}
+}
+
+func TestTopicPublishDelayed(t *testing.T) {
+	topic := newTopic("t", 100)
+	r := &recorder{}
+	topic.Subscribe(r, -1)
+	topic.PublishDelayed(&message.Message{Payload: []byte("late")}, 80*time.Millisecond)
+	topic.PublishDelayed(&message.Message{Payload: []byte("early")}, 20*time.Millisecond)
+	topic.Publish([]byte("now"))
+	if len(r.msgs) != 1 || string(r.msgs[0].Payload) != "now" || topic.Info().Delayed != 2 {
+		t.Fatalf("延迟消息不能立即投递: %d 条消息, %d 条延迟消息", len(r.msgs), topic.Info().Delayed)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for topic.Info().Delayed > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+	want := []string{"now", "early", "late"}
+	if len(r.msgs) != len(want) {
+		t.Fatalf("收到 %d 条消息, want %d", len(r.msgs), len(want))
+	}
+	for i, msg := range r.msgs {
+		if string(msg.Payload) != want[i] || msg.Offset != int64(i) {
+			t.Errorf("第 %d 条消息为 %s@%d, want %s@%d", i, msg.Payload, msg.Offset, want[i], i)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package broker
+
+// rateWindow 计算速率使用的采样数, 每秒采样一次, 即最近10秒的平均速率
+const rateWindow = 10
+
+// meter 记录累计次数并计算最近 rateWindow 秒的平均速率, 不是并发安全的, 由所属的主题或者队列加锁
+type meter struct {
+	total   int64
+	samples [rateWindow + 1]int64 // 最近的累计次数采样, 环形存储
+	pos     int                   // 下一次采样的位置
+	count   int                   // 已经采样的次数, 最多为 len(samples)
+}
+
+// mark 增加累计次数
+func (m *meter) mark(n int) {
+	m.total += int64(n)
+}
+
+// tick 每秒调用一次, 记录当前的累计次数
+func (m *meter) tick() {
+	m.samples[m.pos] = m.total
+	m.pos = (m.pos + 1) % len(m.samples)
+	if m.count < len(m.samples) {
+		m.count++
+	}
+}
+
+// rate 最近的平均速率, 单位 次/秒, 采样不足两次时为 0
+func (m *meter) rate() float64 {
+	if m.count < 2 {
+		return 0
+	}
+	n := len(m.samples)
+	last := m.samples[(m.pos-1+n)%n]
+	first := m.samples[(m.pos-m.count+n)%n]
+	return float64(last-first) / float64(m.count-1)
+}
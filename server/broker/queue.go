@@ -0,0 +1,316 @@
+package broker
+
+import (
+	"github.com/AdeMQ/datastruct/linear"
+	"github.com/AdeMQ/protocol/message"
+	"sort"
+	"sync"
+	"time"
+)
+
+// inflight 已经被取出等待确认的消息
+type inflight struct {
+	msg      *message.Message
+	deadline time.Time
+	owner    Subscriber // 推送消息的消费者, 通过 Pop 取出的消息为 nil
+}
+
+// consumer 以推送方式消费队列的消费者
+type consumer struct {
+	sub    Subscriber
+	credit int // 还可以推送的未确认消息数
+}
+
+// Queue 队列, 每条消息只会被一个消费者取出, 取出之后需要在超时时间内确认, 否则重新投递
+// 消费者可以通过 Pop 主动拉取消息, 也可以通过 Consume 注册之后由队列按照预取数量轮流推送
+type Queue struct {
+	name       string
+	ackTimeout time.Duration
+	mu         sync.Mutex
+	ready      *linear.LinkedList // 等待投递的消息
+	inflight   map[uint64]*inflight
+	nextID     uint64
+	consumers  []*consumer
+	next       int   // 下一个轮询的消费者
+	bytes      int64 // 等待投递与等待确认的消息内容字节数
+	pushed     meter
+	delivered  meter
+	acked      meter
+}
+
+func newQueue(name string, ackTimeout time.Duration) *Queue {
+	return &Queue{
+		name:       name,
+		ackTimeout: ackTimeout,
+		ready:      linear.NewLinkedList(),
+		inflight:   make(map[uint64]*inflight),
+	}
+}
+
+// Consume 注册推送消费者, prefetch 为最多推送的未确认消息数, 重复注册时更新预取数量
+func (q *Queue) Consume(sub Subscriber, prefetch int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	c := q.consumer(sub)
+	if c == nil {
+		c = &consumer{sub: sub}
+		q.consumers = append(q.consumers, c)
+	}
+	// 已经推送但尚未确认的消息占用预取数量
+	c.credit = prefetch
+	for _, f := range q.inflight {
+		if f.owner == sub {
+			c.credit--
+		}
+	}
+	q.dispatch()
+}
+
+// StopConsume 取消推送消费者, 已经推送的消息仍然可以确认
+// requeue 为 true 时该消费者尚未确认的消息立即放回队列头部, 用于连接断开
+func (q *Queue) StopConsume(sub Subscriber, requeue bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if c := q.consumer(sub); c != nil {
+		q.remove(c)
+	}
+	if !requeue {
+		return
+	}
+	var owned []uint64
+	for id, f := range q.inflight {
+		if f.owner == sub {
+			owned = append(owned, id)
+		}
+	}
+	q.requeue(owned)
+	q.dispatch()
+}
+
+// consumer 查找推送消费者, 调用时需要持有锁
+func (q *Queue) consumer(sub Subscriber) *consumer {
+	for _, c := range q.consumers {
+		if c.sub == sub {
+			return c
+		}
+	}
+	return nil
+}
+
+// dispatch 将等待投递的消息轮流推送给还有预取数量的消费者, 调用时需要持有锁
+// 推送失败的消息放回队列头部, 推送失败的消费者已经断开, 不再参与轮询
+func (q *Queue) dispatch() {
+	for q.ready.Length() > 0 {
+		var c *consumer
+		for i := 0; i < len(q.consumers); i++ {
+			candidate := q.consumers[(q.next+i)%len(q.consumers)]
+			if candidate.credit > 0 {
+				c = candidate
+				q.next = (q.next + i + 1) % len(q.consumers)
+				break
+			}
+		}
+		if c == nil {
+			return
+		}
+		e, _ := q.ready.LPop()
+		msg := e.(*message.Message)
+		if !c.sub.Deliver(msg) {
+			q.ready.LPush(msg)
+			q.remove(c)
+			continue
+		}
+		q.inflight[msg.ID] = &inflight{msg: msg, deadline: time.Now().Add(q.ackTimeout), owner: c.sub}
+		c.credit--
+		q.delivered.mark(1)
+	}
+}
+
+// remove 移除推送消费者, 调用时需要持有锁
+func (q *Queue) remove(c *consumer) {
+	for i, candidate := range q.consumers {
+		if candidate == c {
+			q.consumers = append(q.consumers[:i], q.consumers[i+1:]...)
+			if i < q.next {
+				q.next--
+			}
+			break
+		}
+	}
+	if q.next >= len(q.consumers) {
+		q.next = 0
+	}
+}
+
+// release 消息确认或者重新投递之后归还推送消费者的预取数量, 调用时需要持有锁
+func (q *Queue) release(f *inflight) {
+	if f.owner == nil {
+		return
+	}
+	if c := q.consumer(f.owner); c != nil {
+		c.credit++
+	}
+}
+
+// requeue 将未确认的消息按照编号顺序放回队列头部, 调用时需要持有锁
+func (q *Queue) requeue(ids []uint64) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	for _, id := range ids {
+		f := q.inflight[id]
+		q.release(f)
+		q.ready.LPush(f.msg)
+		delete(q.inflight, id)
+	}
+}
+
+// setAckTimeout 修改确认超时时间, 对之后取出的消息生效
+func (q *Queue) setAckTimeout(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ackTimeout = d
+}
+
+// Push 向队列尾部写入一条消息
+func (q *Queue) Push(payload []byte) *message.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	msg := &message.Message{
+		Queue:     q.name,
+		ID:        q.nextID,
+		Payload:   payload,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	q.ready.RPush(msg)
+	q.bytes += int64(len(payload))
+	q.pushed.mark(1)
+	q.dispatch()
+	return msg
+}
+
+// Pop 从队列头部取出一条消息, 队列为空时返回 nil
+func (q *Queue) Pop() *message.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, err := q.ready.LPop()
+	if err != nil {
+		return nil
+	}
+	msg := e.(*message.Message)
+	q.inflight[msg.ID] = &inflight{msg: msg, deadline: time.Now().Add(q.ackTimeout)}
+	q.delivered.mark(1)
+	return msg
+}
+
+// Ack 确认消息已经处理完成, 消息不存在或者已经超时重新投递时返回 false
+func (q *Queue) Ack(id uint64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	f, ok := q.inflight[id]
+	if !ok {
+		return false
+	}
+	delete(q.inflight, id)
+	q.bytes -= int64(len(f.msg.Payload))
+	q.acked.mark(1)
+	q.release(f)
+	q.dispatch()
+	return true
+}
+
+// Nack 拒绝消息, 消息重新放回队列头部等待再次投递
+func (q *Queue) Nack(id uint64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.inflight[id]; !ok {
+		return false
+	}
+	q.requeue([]uint64{id})
+	q.dispatch()
+	return true
+}
+
+// requeueExpired 将超时未确认的消息按照编号顺序放回队列头部
+func (q *Queue) requeueExpired(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var expired []uint64
+	for id, f := range q.inflight {
+		if now.After(f.deadline) {
+			expired = append(expired, id)
+		}
+	}
+	q.requeue(expired)
+	q.dispatch()
+}
+
+// Info 队列的消息统计
+func (q *Queue) Info() message.QueueInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return message.QueueInfo{
+		Name:        q.name,
+		Ready:       q.ready.Length(),
+		Inflight:    len(q.inflight),
+		Bytes:       q.bytes,
+		Consumers:   len(q.consumers),
+		Pushed:      q.pushed.total,
+		Acked:       q.acked.total,
+		PushRate:    q.pushed.rate(),
+		DeliverRate: q.delivered.rate(),
+		AckRate:     q.acked.rate(),
+	}
+}
+
+// tick 采样消息速率
+func (q *Queue) tick() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pushed.tick()
+	q.delivered.tick()
+	q.acked.tick()
+}
+
+// Peek 查看队列头部即将投递的最多 n 条消息, 不会取出消息
+func (q *Queue) Peek(n int) []*message.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := make([]*message.Message, 0, n)
+	for _, e := range q.ready.Range(0, n) {
+		msgs = append(msgs, e.(*message.Message))
+	}
+	return msgs
+}
+
+// Browse 查看队列中的消息, 不会取出消息也不影响确认的截止时间
+// 消息按照等待投递的顺序排列, 之后是按照编号排列的等待确认的消息, from 为消息在其中的位置
+func (q *Queue) Browse(from, limit int) message.Browse {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ret := message.Browse{Messages: []message.Browsed{}, Next: int64(from)}
+	for _, e := range q.ready.Range(from, limit) {
+		ret.Messages = append(ret.Messages, message.Browsed{Message: *e.(*message.Message), State: "ready"})
+	}
+	if n := limit - len(ret.Messages); n > 0 {
+		ids := make([]uint64, 0, len(q.inflight))
+		for id := range q.inflight {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		start := from - q.ready.Length()
+		if start < 0 {
+			start = 0
+		}
+		for i := start; i < len(ids) && n > 0; i, n = i+1, n-1 {
+			f := q.inflight[ids[i]]
+			ret.Messages = append(ret.Messages, message.Browsed{
+				Message:  *f.msg,
+				State:    "inflight",
+				Deadline: f.deadline.UnixNano() / int64(time.Millisecond),
+			})
+		}
+	}
+	ret.Next += int64(len(ret.Messages))
+	ret.More = ret.Next < int64(q.ready.Length()+len(q.inflight))
+	return ret
+}
@@ -0,0 +1,263 @@
+package broker
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Subscriber 主题的订阅者
+type Subscriber interface {
+	// Deliver 投递消息, 可能持有主题或者队列的锁调用, 实现只能有限时间阻塞
+	// 返回 false 表示订阅者已经关闭或者被断开, 消息没有送达
+	Deliver(msg *message.Message) bool
+}
+
+// replayBatch 订阅时每次在锁内取出的补发消息条数, 投递在锁外进行
+const replayBatch = 256
+
+// Topic 主题, 消息按照写入顺序分配递增的偏移量, 投递给所有的订阅者
+type Topic struct {
+	name      string
+	retention int
+	mu        sync.Mutex
+	messages  []*message.Message // 保留的消息, 偏移量连续递增
+	next      int64              // 下一条消息的偏移量
+	bytes     int64              // 保留的消息内容字节数
+	subs      map[Subscriber]bool
+	delayed   []delayedMessage // 延迟投递的消息, 按照到期时间排序
+	timer     *time.Timer      // 最早到期的延迟消息的定时器
+	published meter
+	delivered meter
+}
+
+// delayedMessage 延迟投递的消息, 到期之后才分配偏移量并投递
+type delayedMessage struct {
+	msg *message.Message
+	due time.Time
+}
+
+func newTopic(name string, retention int) *Topic {
+	return &Topic{
+		name:      name,
+		retention: retention,
+		subs:      make(map[Subscriber]bool),
+	}
+}
+
+// Publish 写入一条消息并投递给所有订阅者
+func (t *Topic) Publish(payload []byte) *message.Message {
+	return t.PublishBatch([][]byte{payload})[0]
+}
+
+// PublishMessage 写入一条带有消息键与消息头的消息, 偏移量与写入时间由主题分配
+func (t *Topic) PublishMessage(msg *message.Message) *message.Message {
+	return t.publish([]*message.Message{msg})[0]
+}
+
+// PublishBatch 写入多条消息, 同一批次的消息分配连续的偏移量, 不会与其他写入交错
+func (t *Topic) PublishBatch(payloads [][]byte) []*message.Message {
+	msgs := make([]*message.Message, 0, len(payloads))
+	for _, payload := range payloads {
+		msgs = append(msgs, &message.Message{Payload: payload})
+	}
+	return t.publish(msgs)
+}
+
+// PublishDelayed 写入一条延迟投递的消息, 返回预计的投递时间
+// 消息在 delay 之后才分配偏移量并投递给订阅者, 到期之前不会被订阅者以及 browse 看到
+func (t *Topic) PublishDelayed(msg *message.Message, delay time.Duration) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	due := time.Now().Add(delay)
+	// 到期时间相同的消息按照写入顺序投递
+	i := sort.Search(len(t.delayed), func(i int) bool { return t.delayed[i].due.After(due) })
+	t.delayed = append(t.delayed, delayedMessage{})
+	copy(t.delayed[i+1:], t.delayed[i:])
+	t.delayed[i] = delayedMessage{msg: msg, due: due}
+	t.schedule()
+	return due
+}
+
+// schedule 按照最早到期的延迟消息设置定时器, 调用时需要持有锁
+func (t *Topic) schedule() {
+	if len(t.delayed) == 0 {
+		return
+	}
+	wait := time.Until(t.delayed[0].due)
+	if t.timer == nil {
+		t.timer = time.AfterFunc(wait, t.flushDelayed)
+		return
+	}
+	t.timer.Reset(wait)
+}
+
+// flushDelayed 投递所有到期的延迟消息, 由定时器调用
+func (t *Topic) flushDelayed() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for n < len(t.delayed) && !t.delayed[n].due.After(now) {
+		n++
+	}
+	if n > 0 {
+		msgs := make([]*message.Message, n)
+		for i := range msgs {
+			msgs[i] = t.delayed[i].msg
+		}
+		t.delayed = append([]delayedMessage(nil), t.delayed[n:]...)
+		t.publishLocked(msgs)
+	}
+	t.schedule()
+}
+
+// publish 为消息分配偏移量以及写入时间之后保存, 并投递给所有订阅者
+func (t *Topic) publish(msgs []*message.Message) []*message.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.publishLocked(msgs)
+}
+
+// publishLocked 同 publish, 调用时需要持有锁
+func (t *Topic) publishLocked(msgs []*message.Message) []*message.Message {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for _, msg := range msgs {
+		msg.Topic = t.name
+		msg.Offset = t.next
+		msg.Timestamp = now
+		t.next++
+		t.bytes += int64(len(msg.Payload))
+		t.messages = append(t.messages, msg)
+	}
+	t.trim()
+	delivered := 0
+	for _, msg := range msgs {
+		for sub := range t.subs {
+			if sub.Deliver(msg) {
+				delivered++
+			}
+		}
+	}
+	t.published.mark(len(msgs))
+	t.delivered.mark(delivered)
+	return msgs
+}
+
+// trim 丢弃超出保留条数的最早的消息, 调用时需要持有锁
+func (t *Topic) trim() {
+	if len(t.messages) <= t.retention {
+		return
+	}
+	dropped := len(t.messages) - t.retention
+	for _, msg := range t.messages[:dropped] {
+		t.bytes -= int64(len(msg.Payload))
+	}
+	// 重新分配底层数组, 避免被丢弃的消息一直被引用
+	t.messages = append([]*message.Message(nil), t.messages[dropped:]...)
+}
+
+// setRetention 修改保留条数, 超出的消息立即丢弃
+func (t *Topic) setRetention(retention int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.retention = retention
+	t.trim()
+}
+
+// Subscribe 订阅主题, from 大于等于0时先补发保留的偏移量不小于 from 的消息
+// 补发按照 replayBatch 分批在锁外投递, 不会阻塞其他订阅者, 补发完成时在同一次加锁中注册订阅, 不会漏掉消息
+// 补发期间被丢弃的消息不再补发, 订阅者在补发时断开返回 false 并且不会注册订阅
+func (t *Topic) Subscribe(sub Subscriber, from int64) bool {
+	for {
+		t.mu.Lock()
+		var batch []*message.Message
+		if from >= 0 {
+			first := t.next - int64(len(t.messages))
+			if from < first {
+				from = first
+			}
+			end := from - first + replayBatch
+			if end > int64(len(t.messages)) {
+				end = int64(len(t.messages))
+			}
+			if from-first < end {
+				batch = append(batch, t.messages[from-first:end]...)
+			}
+		}
+		if len(batch) == 0 {
+			t.subs[sub] = true
+			t.mu.Unlock()
+			return true
+		}
+		t.mu.Unlock()
+		delivered := 0
+		for _, msg := range batch {
+			if !sub.Deliver(msg) {
+				t.markDelivered(delivered)
+				return false
+			}
+			delivered++
+		}
+		t.markDelivered(delivered)
+		from = batch[len(batch)-1].Offset + 1
+	}
+}
+
+// markDelivered 记录锁外投递的消息数
+func (t *Topic) markDelivered(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.delivered.mark(n)
+}
+
+// Unsubscribe 取消订阅
+func (t *Topic) Unsubscribe(sub Subscriber) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.subs, sub)
+}
+
+// Info 主题的消息统计
+func (t *Topic) Info() message.TopicInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return message.TopicInfo{
+		Name:        t.name,
+		Messages:    len(t.messages),
+		Bytes:       t.bytes,
+		FirstOffset: t.next - int64(len(t.messages)),
+		NextOffset:  t.next,
+		Subscribers: len(t.subs),
+		Delayed:     len(t.delayed),
+		PublishRate: t.published.rate(),
+		DeliverRate: t.delivered.rate(),
+	}
+}
+
+// tick 采样消息速率
+func (t *Topic) tick() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.published.tick()
+	t.delivered.tick()
+}
+
+// Browse 查看偏移量不小于 from 的最多 limit 条保留的消息, 不影响订阅者
+// from 早于最早保留的消息时从最早的消息开始
+func (t *Topic) Browse(from int64, limit int) message.Browse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	first := t.next - int64(len(t.messages))
+	if from < first {
+		from = first
+	}
+	ret := message.Browse{Messages: []message.Browsed{}, Next: from}
+	for i := from - first; i < int64(len(t.messages)) && len(ret.Messages) < limit; i++ {
+		ret.Messages = append(ret.Messages, message.Browsed{Message: *t.messages[i]})
+		ret.Next = t.messages[i].Offset + 1
+	}
+	ret.More = ret.Next < t.next
+	return ret
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"github.com/AdeMQ/server/auth"
+	"github.com/AdeMQ/server/logger"
+	"net"
+)
+
+// auth 认证握手, 命令格式: auth <user> <password>
+func (d *Dispatcher) auth(s *Session, req *message.Request) *message.Response {
+	if len(req.Params) != 2 {
+		return message.Error(message.CodeBadRequest, "命令格式: auth <user> <password>")
+	}
+	if !d.Auth.Enabled() {
+		return message.OK("auth disabled")
+	}
+	user := req.Params[0]
+	err := d.Auth.Verify(user, req.Params[1], remoteHost(s.RemoteAddr))
+	switch err {
+	case nil:
+		s.User = user
+		s.Authenticated = true
+		logger.Info("Auth success", user, s.RemoteAddr)
+		return message.OK("ok")
+	case auth.ErrLocked:
+		logger.Warn("Auth locked", user, s.RemoteAddr)
+		return message.Error(message.CodeLocked, err.Error())
+	default:
+		logger.Warn("Auth failed", user, s.RemoteAddr)
+		return message.Error(message.CodeUnauthorized, err.Error())
+	}
+}
+
+// remoteHost 客户端地址中的主机部分, 同一主机的多个连接共用认证失败的计数
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
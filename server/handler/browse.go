@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"github.com/AdeMQ/server/auth"
+	"strconv"
+)
+
+// peek 查看队列头部即将投递的消息, 命令格式: peek <queue> [n], n 默认为1
+// 消息不会被取出, 也不会影响消息的投递与确认
+func (d *Dispatcher) peek(s *Session, req *message.Request) *message.Response {
+	n := 1
+	if len(req.Params) > 1 {
+		var resp *message.Response
+		if n, resp = browseCount(req.Params[1]); resp != nil {
+			return resp
+		}
+	}
+	q, ok := d.Broker.FindQueue(req.Params[0])
+	if !ok {
+		return message.Error(message.CodeNotFound, "队列不存在")
+	}
+	return message.OK(q.Peek(n))
+}
+
+// browse 分页查看主题或者队列中的消息, 命令格式: browse <topic|queue> <name> [from] [limit]
+// 主题的 from 为偏移量, 默认从最早保留的消息开始; 队列的 from 为消息的位置, 等待投递的消息在前, 等待确认的消息在后
+// limit 默认为 20, 查看消息不会改变消息的投递状态, 也不会创建不存在的主题或者队列
+func (d *Dispatcher) browse(s *Session, req *message.Request) *message.Response {
+	if len(req.Params) < 2 {
+		return message.Error(message.CodeBadRequest, "命令格式: browse <topic|queue> <name> [from] [limit]")
+	}
+	resource, name := req.Params[0], req.Params[1]
+	if resource != auth.ResourceTopic && resource != auth.ResourceQueue {
+		return message.Error(message.CodeBadRequest, "资源类型需要为 topic 或者 queue")
+	}
+	if resp := d.allow(s, req, resource, name, auth.PermConsume); resp != nil {
+		return resp
+	}
+	var from int64
+	if len(req.Params) > 2 {
+		n, err := strconv.ParseInt(req.Params[2], 10, 64)
+		if err != nil || n < 0 {
+			return message.Error(message.CodeBadRequest, "from 格式错误")
+		}
+		from = n
+	}
+	limit := 20
+	if len(req.Params) > 3 {
+		var resp *message.Response
+		if limit, resp = browseCount(req.Params[3]); resp != nil {
+			return resp
+		}
+	}
+	if resource == auth.ResourceTopic {
+		t, ok := d.Broker.FindTopic(name)
+		if !ok {
+			return message.Error(message.CodeNotFound, "主题不存在")
+		}
+		return message.OK(t.Browse(from, limit))
+	}
+	q, ok := d.Broker.FindQueue(name)
+	if !ok {
+		return message.Error(message.CodeNotFound, "队列不存在")
+	}
+	return message.OK(q.Browse(int(from), limit))
+}
+
+// browseCount 解析查看的消息数, 需要为 1 到 ConstMaxBrowse 之间的整数
+func browseCount(param string) (int, *message.Response) {
+	n, err := strconv.Atoi(param)
+	if err != nil || n <= 0 || n > ConstMaxBrowse {
+		return 0, message.Error(message.CodeBadRequest, "消息数需要为 1 到 "+strconv.Itoa(ConstMaxBrowse)+" 之间的整数")
+	}
+	return n, nil
+}
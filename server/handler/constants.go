@@ -0,0 +1,38 @@
+package handler
+
+import "time"
+
+const ConstAck = "ack"
+const ConstAuth = "auth"
+const ConstBrowse = "browse"
+const ConstConsume = "consume"
+const ConstHeartbeat = "heartbeat"
+const ConstHello = "hello"
+const ConstInfo = "info"
+const ConstList = "list"
+const ConstMemStats = "memstats"
+const ConstMPublish = "mpublish"
+const ConstNack = "nack"
+const ConstPeek = "peek"
+const ConstPing = "ping"
+const ConstPop = "pop"
+const ConstPublish = "publish"
+const ConstPush = "push"
+const ConstSubscribe = "subscribe"
+const ConstUnconsume = "unconsume"
+const ConstUnsubscribe = "unsubscribe"
+
+// ConstMaxPrefetch 推送消费者最大的预取数量
+const ConstMaxPrefetch = 1000
+
+// ConstPushTimeout 推送消息时等待发送通道的最长时间, 超时的客户端会被断开
+const ConstPushTimeout = 5 * time.Second
+
+// ConstMaxDelay 延迟消息最长的延迟时间, 消息只保存在内存中, 不适合长时间延迟
+const ConstMaxDelay = 24 * time.Hour
+
+// ConstMaxBrowse peek 与 browse 命令单次最多返回的消息数
+const ConstMaxBrowse = 1000
+
+// Version 服务端版本号, 通过 info 命令返回
+const Version = "0.1.0"
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/AdeMQ/protocol/message"
+	"github.com/AdeMQ/protocol/packet"
+	"github.com/AdeMQ/server/auth"
+	"github.com/AdeMQ/server/broker"
+	"github.com/AdeMQ/server/limiter"
+	"github.com/AdeMQ/server/logger"
+	"strings"
+	"time"
+)
+
+// HandleFunc 服务端命令处理函数, 返回 nil 表示不需要回复客户端
+type HandleFunc func(s *Session, req *message.Request) *message.Response
+
+// Command 服务端命令定义
+type Command struct {
+	Handle    HandleFunc
+	Anonymous bool      // 未认证时是否允许执行
+	Unlimited bool      // 是否跳过限流
+	Perm      auth.Perm // 执行命令需要的权限, 为空表示不做权限校验
+	// Resource 命令操作的资源类型, 不为空时约定命令的第一个参数为资源名称
+	Resource string
+}
+
+// Dispatcher 服务端命令分发器
+type Dispatcher struct {
+	Auth     *auth.Authenticator
+	Limiter  *limiter.Limiter
+	Compress *packet.CompressConfig
+	Broker   *broker.Broker
+	Commands map[string]*Command
+	// ConfigInfo 返回 info 命令中的配置摘要, 由服务启动时设置
+	ConfigInfo func() *message.ConfigInfo
+	started    time.Time
+	connected  int64 // 当前的连接数
+	totalConns int64 // 启动以来建立的连接总数
+}
+
+// NewDispatcher 创建服务端命令分发器
+func NewDispatcher(authenticator *auth.Authenticator, lim *limiter.Limiter, compress *packet.CompressConfig, b *broker.Broker) *Dispatcher {
+	d := &Dispatcher{
+		Auth:     authenticator,
+		Limiter:  lim,
+		Compress: compress,
+		Broker:   b,
+		started:  time.Now(),
+	}
+	d.Commands = d.initCommands()
+	return d
+}
+
+// Dispatch 解析客户端发送的完整消息体并分发到对应的处理函数
+func (d *Dispatcher) Dispatch(s *Session, content []byte) *message.Response {
+	req, err := message.DecodeRequest(content)
+	if err != nil {
+		// 格式错误的请求同样消耗限流配额, 避免客户端不受限制地发送无效数据
+		if resp := d.checkLimit(s, 1, len(content)); resp != nil {
+			return resp
+		}
+		return message.Error(message.CodeBadRequest, "请求格式错误")
+	}
+	resp := d.dispatch(s, req, len(content))
+	if resp != nil {
+		resp.ID = req.ID
+	}
+	return resp
+}
+
+func (d *Dispatcher) dispatch(s *Session, req *message.Request, size int) *message.Response {
+	req.Cmd = strings.ToLower(req.Cmd)
+	cmd, ok := d.Commands[req.Cmd]
+	// 在校验命令、认证与权限之前申请配额, 不存在的命令以及被拒绝的请求同样受到限流
+	// 未认证的连接只做连接级别的限制
+	// 批量写入的请求按照其中的消息条数计算消息配额
+	if !ok || !cmd.Unlimited {
+		count := 1
+		if len(req.Batch) > 0 {
+			count = len(req.Batch)
+		}
+		if resp := d.checkLimit(s, count, size); resp != nil {
+			return resp
+		}
+	}
+	if !ok {
+		return message.Error(message.CodeNotFound, "命令不存在")
+	}
+	// 开启认证之后, 除握手相关命令外都需要先通过认证
+	if d.Auth.Enabled() && !s.Authenticated && !cmd.Anonymous {
+		return message.Error(message.CodeUnauthorized, "请先使用 auth 命令进行认证")
+	}
+	if resp := d.checkPerm(s, req, cmd); resp != nil {
+		return resp
+	}
+	return cmd.Handle(s, req)
+}
+
+// checkLimit 申请限流配额, 配额不足时按照配置延迟处理或者返回限流的响应
+// 由于消息是在读取协程中同步处理的, 延迟处理同时也会延迟从连接中读取后续的数据
+func (d *Dispatcher) checkLimit(s *Session, count, size int) *message.Response {
+	ok, wait := s.Limit.Acquire(s.User, count, size)
+	if !ok {
+		retryAfter := wait.Milliseconds() + 1
+		return &message.Response{
+			Code: message.CodeThrottled,
+			Msg:  fmt.Sprintf("请求过于频繁, 请 %dms 后重试", retryAfter),
+			Data: map[string]int64{"retryAfter": retryAfter},
+		}
+	}
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return nil
+}
+
+// checkPerm 校验当前用户是否拥有执行命令的权限, 无权限时返回拒绝的响应
+func (d *Dispatcher) checkPerm(s *Session, req *message.Request, cmd *Command) *message.Response {
+	if cmd.Perm == "" {
+		return nil
+	}
+	name := ""
+	if cmd.Resource != "" {
+		if len(req.Params) == 0 {
+			return message.Error(message.CodeBadRequest, "缺少 "+cmd.Resource+" 名称")
+		}
+		name = req.Params[0]
+	}
+	return d.allow(s, req, cmd.Resource, name, cmd.Perm)
+}
+
+// allow 校验当前用户对资源的权限, 无权限时返回拒绝的响应, 用于资源类型由参数决定的命令
+func (d *Dispatcher) allow(s *Session, req *message.Request, resource, name string, perm auth.Perm) *message.Response {
+	if d.Auth.Allow(s.User, resource, name, perm) {
+		return nil
+	}
+	logger.Warn("ACL denied", s.User, req.Cmd, resource, name, s.RemoteAddr)
+	return message.Error(message.CodeForbidden, "没有 "+string(perm)+" 权限")
+}
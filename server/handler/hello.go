@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"github.com/AdeMQ/protocol/message"
+)
+
+// hello 连接建立时的协商, 命令格式: hello [codec...]
+// 参数为客户端支持的压缩算法, 按照偏好顺序排列, 服务端选出双方都支持的第一个算法
+func (d *Dispatcher) hello(s *Session, req *message.Request) *message.Response {
+	ret := message.Hello{}
+	if codec := d.Compress.Negotiate(req.Params); codec != nil {
+		ret.Codec = codec.Name()
+		ret.Threshold = d.Compress.Threshold
+		s.Conn.SetCodec(codec, ret.Threshold)
+	}
+	return message.OK(ret)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"github.com/AdeMQ/protocol/packet"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// info 查看服务端的运行状态, 命令格式: info [section]
+// section 为 message.InfoSections 中的一项, 不指定时返回全部分类
+func (d *Dispatcher) info(s *Session, req *message.Request) *message.Response {
+	section := "all"
+	if len(req.Params) > 0 {
+		section = strings.ToLower(req.Params[0])
+	}
+	want := func(name string) bool {
+		return section == "all" || section == name
+	}
+	if section != "all" && !contains(message.InfoSections, section) {
+		return message.Error(message.CodeBadRequest, "分类需要为 "+strings.Join(message.InfoSections, "|"))
+	}
+
+	ret := &message.Info{}
+	if want("server") {
+		ret.Server = &message.ServerInfo{
+			Version:   Version,
+			GoVersion: runtime.Version(),
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			PID:       os.Getpid(),
+			StartTime: d.started.UnixNano() / int64(time.Millisecond),
+			Uptime:    int64(time.Since(d.started).Seconds()),
+		}
+	}
+	if want("config") && d.ConfigInfo != nil {
+		ret.Config = d.ConfigInfo()
+	}
+	// 连接统计与存储统计都需要汇总所有主题与队列
+	topics, queues := d.Broker.Info()
+	if want("clients") {
+		ret.Clients = &message.ClientsInfo{
+			Connected: int(atomic.LoadInt64(&d.connected)),
+			Total:     atomic.LoadInt64(&d.totalConns),
+		}
+		for _, t := range topics {
+			ret.Clients.Subscriptions += t.Subscribers
+		}
+		for _, q := range queues {
+			ret.Clients.Consumers += q.Consumers
+		}
+	}
+	if want("topics") {
+		ret.Topics = topics
+	}
+	if want("queues") {
+		ret.Queues = queues
+	}
+	if want("memory") {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		ret.Memory = &message.MemoryInfo{
+			HeapAlloc:  m.HeapAlloc,
+			HeapInuse:  m.HeapInuse,
+			HeapIdle:   m.HeapIdle,
+			Sys:        m.Sys,
+			NumGC:      m.NumGC,
+			Goroutines: runtime.NumGoroutine(),
+			BufferPool: packet.DefaultPool.Stats().InUseBytes,
+		}
+	}
+	if want("storage") {
+		// 目前消息只保存在内存中
+		ret.Storage = &message.StorageInfo{Engine: "memory", Topics: len(topics), Queues: len(queues)}
+		for _, t := range topics {
+			ret.Storage.Messages += int64(t.Messages + t.Delayed)
+			ret.Storage.Bytes += t.Bytes
+		}
+		for _, q := range queues {
+			ret.Storage.Messages += int64(q.Ready + q.Inflight)
+			ret.Storage.Bytes += q.Bytes
+		}
+	}
+	if want("replication") {
+		// 目前只支持单机部署
+		ret.Replication = &message.ReplicationInfo{Role: "standalone"}
+	}
+	return message.OK(ret)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
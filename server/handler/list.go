@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"github.com/AdeMQ/server/auth"
+)
+
+// list 列出当前用户有权限访问的主题或者队列名称, 命令格式: list <topic|queue>
+func (d *Dispatcher) list(s *Session, req *message.Request) *message.Response {
+	if len(req.Params) == 0 {
+		return message.Error(message.CodeBadRequest, "缺少资源类型 topic|queue")
+	}
+	var names []string
+	resource := req.Params[0]
+	switch resource {
+	case auth.ResourceTopic:
+		names = d.Broker.TopicNames()
+	case auth.ResourceQueue:
+		names = d.Broker.QueueNames()
+	default:
+		return message.Error(message.CodeBadRequest, "资源类型需要为 topic 或者 queue")
+	}
+	allowed := make([]string, 0, len(names))
+	for _, name := range names {
+		if d.Auth.Allow(s.User, resource, name, auth.PermConsume) || d.Auth.Allow(s.User, resource, name, auth.PermPublish) {
+			allowed = append(allowed, name)
+		}
+	}
+	return message.OK(allowed)
+}
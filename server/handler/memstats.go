@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"github.com/AdeMQ/protocol/packet"
+	"runtime"
+)
+
+// MemStats 内存统计信息
+type MemStats struct {
+	BufferPool packet.PoolStats `json:"bufferPool"`
+	HeapAlloc  uint64           `json:"heapAlloc"`
+	HeapInuse  uint64           `json:"heapInuse"`
+	HeapIdle   uint64           `json:"heapIdle"`
+	Sys        uint64           `json:"sys"`
+	NumGC      uint32           `json:"numGC"`
+	Goroutines int              `json:"goroutines"`
+}
+
+// memstats 获取读取缓冲池以及运行时的内存统计, 用于评估 bufLen 与 bufMaxLen 的配置是否合理
+// 缓冲池中各尺寸等级的获取次数反映了连接实际需要的缓冲区大小分布, grows 过多说明 bufLen 偏小
+func (d *Dispatcher) memstats(s *Session, req *message.Request) *message.Response {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return message.OK(MemStats{
+		BufferPool: packet.DefaultPool.Stats(),
+		HeapAlloc:  m.HeapAlloc,
+		HeapInuse:  m.HeapInuse,
+		HeapIdle:   m.HeapIdle,
+		Sys:        m.Sys,
+		NumGC:      m.NumGC,
+		Goroutines: runtime.NumGoroutine(),
+	})
+}
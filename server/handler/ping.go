@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"github.com/AdeMQ/protocol/message"
+)
+
+// ping 连通性检测
+func (d *Dispatcher) ping(s *Session, req *message.Request) *message.Response {
+	return message.OK("pong")
+}
+
+// heartbeat 心跳包, 命令格式: heartbeat [timestamp]
+// 携带发送时间戳的心跳原样返回时间戳, 客户端据此计算往返延迟; 不携带参数的心跳只用于维持连接, 不需要回复
+func (d *Dispatcher) heartbeat(s *Session, req *message.Request) *message.Response {
+	if len(req.Params) == 0 {
+		return nil
+	}
+	return message.OK(req.Params[0])
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"strconv"
+)
+
+// push 向队列写入消息, 命令格式: push <queue> [payload]
+func (d *Dispatcher) push(s *Session, req *message.Request) *message.Response {
+	msg := d.Broker.Queue(req.Params[0]).Push(payload(req))
+	return message.OK(map[string]uint64{"id": msg.ID})
+}
+
+// pop 从队列取出一条消息, 命令格式: pop <queue>, 队列为空时 Data 为空
+// 取出的消息需要通过 ack 确认, 超时未确认的消息会重新投递
+func (d *Dispatcher) pop(s *Session, req *message.Request) *message.Response {
+	msg := d.Broker.Queue(req.Params[0]).Pop()
+	if msg == nil {
+		return message.OK(nil)
+	}
+	return message.OK(msg)
+}
+
+// ack 确认消息, 命令格式: ack <queue> <id>
+func (d *Dispatcher) ack(s *Session, req *message.Request) *message.Response {
+	id, resp := messageID(req)
+	if resp != nil {
+		return resp
+	}
+	if !d.Broker.Queue(req.Params[0]).Ack(id) {
+		return message.Error(message.CodeNotFound, "消息不存在或者已经超时")
+	}
+	return message.OK("ok")
+}
+
+// nack 拒绝消息, 消息会重新放回队列头部, 命令格式: nack <queue> <id>
+func (d *Dispatcher) nack(s *Session, req *message.Request) *message.Response {
+	id, resp := messageID(req)
+	if resp != nil {
+		return resp
+	}
+	if !d.Broker.Queue(req.Params[0]).Nack(id) {
+		return message.Error(message.CodeNotFound, "消息不存在或者已经超时")
+	}
+	return message.OK("ok")
+}
+
+// messageID 解析 ack/nack 命令中的消息编号
+func messageID(req *message.Request) (uint64, *message.Response) {
+	if len(req.Params) != 2 {
+		return 0, message.Error(message.CodeBadRequest, "命令格式: "+req.Cmd+" <queue> <id>")
+	}
+	id, err := strconv.ParseUint(req.Params[1], 10, 64)
+	if err != nil {
+		return 0, message.Error(message.CodeBadRequest, "消息编号格式错误")
+	}
+	return id, nil
+}
+
+// consume 以推送方式消费队列, 命令格式: consume <queue> [prefetch]
+// 队列按照预取数量将消息推送给客户端, 默认为1, 消息确认之后继续推送
+func (d *Dispatcher) consume(s *Session, req *message.Request) *message.Response {
+	prefetch := 1
+	if len(req.Params) > 1 {
+		n, err := strconv.Atoi(req.Params[1])
+		if err != nil || n <= 0 || n > ConstMaxPrefetch {
+			return message.Error(message.CodeBadRequest, "prefetch 需要在 1-"+strconv.Itoa(ConstMaxPrefetch)+" 之间")
+		}
+		prefetch = n
+	}
+	s.queues[req.Params[0]] = true
+	d.Broker.Queue(req.Params[0]).Consume(s, prefetch)
+	return message.OK("ok")
+}
+
+// unconsume 停止推送队列消息, 已经推送的消息仍然需要确认, 命令格式: unconsume <queue>
+func (d *Dispatcher) unconsume(s *Session, req *message.Request) *message.Response {
+	queue := req.Params[0]
+	if s.queues[queue] {
+		delete(s.queues, queue)
+		d.Broker.Queue(queue).StopConsume(s, false)
+	}
+	return message.OK("ok")
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"github.com/AdeMQ/server/auth"
+)
+
+func (d *Dispatcher) initCommands() map[string]*Command {
+	// 所有新增的命令要通过此处注入进来（请按照字典顺序处理）
+	cmdDict := make(map[string]*Command)
+	cmdDict[ConstAck] = &Command{Handle: d.ack, Perm: auth.PermConsume, Resource: auth.ResourceQueue}
+	cmdDict[ConstAuth] = &Command{Handle: d.auth, Anonymous: true}
+	cmdDict[ConstBrowse] = &Command{Handle: d.browse}
+	cmdDict[ConstConsume] = &Command{Handle: d.consume, Perm: auth.PermConsume, Resource: auth.ResourceQueue}
+	cmdDict[ConstHeartbeat] = &Command{Handle: d.heartbeat, Anonymous: true, Unlimited: true}
+	cmdDict[ConstHello] = &Command{Handle: d.hello, Anonymous: true}
+	cmdDict[ConstInfo] = &Command{Handle: d.info, Perm: auth.PermAdmin}
+	cmdDict[ConstList] = &Command{Handle: d.list}
+	cmdDict[ConstMemStats] = &Command{Handle: d.memstats, Perm: auth.PermAdmin}
+	cmdDict[ConstMPublish] = &Command{Handle: d.mpublish, Perm: auth.PermPublish, Resource: auth.ResourceTopic}
+	cmdDict[ConstNack] = &Command{Handle: d.nack, Perm: auth.PermConsume, Resource: auth.ResourceQueue}
+	cmdDict[ConstPeek] = &Command{Handle: d.peek, Perm: auth.PermConsume, Resource: auth.ResourceQueue}
+	cmdDict[ConstPing] = &Command{Handle: d.ping}
+	cmdDict[ConstPop] = &Command{Handle: d.pop, Perm: auth.PermConsume, Resource: auth.ResourceQueue}
+	cmdDict[ConstPublish] = &Command{Handle: d.publish, Perm: auth.PermPublish, Resource: auth.ResourceTopic}
+	cmdDict[ConstPush] = &Command{Handle: d.push, Perm: auth.PermPublish, Resource: auth.ResourceQueue}
+	cmdDict[ConstSubscribe] = &Command{Handle: d.subscribe, Perm: auth.PermConsume, Resource: auth.ResourceTopic}
+	cmdDict[ConstUnconsume] = &Command{Handle: d.unconsume, Perm: auth.PermConsume, Resource: auth.ResourceQueue}
+	cmdDict[ConstUnsubscribe] = &Command{Handle: d.unsubscribe, Perm: auth.PermConsume, Resource: auth.ResourceTopic}
+	return cmdDict
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"github.com/AdeMQ/protocol/packet"
+	"github.com/AdeMQ/server/limiter"
+	"github.com/AdeMQ/server/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Session 单个客户端连接的会话状态
+type Session struct {
+	Conn          *packet.TcpConn
+	RemoteAddr    string
+	User          string // 认证通过的用户名
+	Authenticated bool   // 是否已经通过认证
+	Limit         *limiter.Conn
+	topics        map[string]bool // 已经订阅的主题
+	queues        map[string]bool // 以推送方式消费的队列
+	mu            sync.RWMutex    // 推送时持有读锁, 关闭会话时持有写锁, 保证关闭发送通道之后不再发送
+	closed        bool
+	done          chan struct{} // 会话关闭或者因为推送超时被断开时关闭, 结束正在等待的推送
+	doneOnce      sync.Once
+}
+
+// NewSession 为新建立的连接创建会话
+func (d *Dispatcher) NewSession(conn *packet.TcpConn) *Session {
+	atomic.AddInt64(&d.connected, 1)
+	atomic.AddInt64(&d.totalConns, 1)
+	return &Session{
+		Conn:       conn,
+		RemoteAddr: conn.Conn.RemoteAddr().String(),
+		Limit:      d.Limiter.NewConn(),
+		topics:     make(map[string]bool),
+		queues:     make(map[string]bool),
+		done:       make(chan struct{}),
+	}
+}
+
+// CloseSession 连接断开时清理会话, 需要在关闭连接的发送通道之前调用
+func (d *Dispatcher) CloseSession(s *Session) {
+	atomic.AddInt64(&d.connected, -1)
+	// 先结束正在等待的推送, 之后才能获取写锁
+	s.stop()
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	for topic := range s.topics {
+		d.Broker.Topic(topic).Unsubscribe(s)
+	}
+	// 连接断开之后无法再确认已经推送的消息, 立即放回队列交给其他消费者
+	for queue := range s.queues {
+		d.Broker.Queue(queue).StopConsume(s, true)
+	}
+}
+
+// Deliver 实现 broker.Subscriber, 将订阅的主题消息以及队列消息推送给客户端, 推送失败时返回 false
+func (s *Session) Deliver(msg *message.Message) bool {
+	return s.Push(&message.Response{Type: message.TypePush, Data: msg})
+}
+
+// Push 通过连接的发送协程向客户端推送消息
+// 发送通道已满时最多等待 ConstPushTimeout, 超时说明客户端读取过慢, 断开连接而不是丢弃消息
+// 会话已经关闭或者被断开时返回 false
+func (s *Session) Push(resp *message.Response) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return false
+	}
+	b := resp.Encode()
+	select {
+	case s.Conn.WritableEventChan <- b:
+		return true
+	case <-s.done:
+		return false
+	default:
+	}
+	timer := time.NewTimer(ConstPushTimeout)
+	defer timer.Stop()
+	select {
+	case s.Conn.WritableEventChan <- b:
+		return true
+	case <-s.done:
+		return false
+	case <-timer.C:
+		logger.Warn("Error Writing 推送超时, 断开读取过慢的客户端", s.RemoteAddr)
+		s.stop()
+		// 关闭底层连接之后读取协程退出并清理会话, 未确认的队列消息会重新投递
+		_ = s.Conn.Conn.Close()
+		return false
+	}
+}
+
+// stop 结束会话的推送, 可以重复调用
+func (s *Session) stop() {
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
+}
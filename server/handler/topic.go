@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/AdeMQ/protocol/message"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// payload 获取请求中的消息内容, 未携带 Payload 时使用第二个及之后的参数以空格拼接
+func payload(req *message.Request) []byte {
+	if len(req.Payload) > 0 || len(req.Params) < 2 {
+		return req.Payload
+	}
+	return []byte(strings.Join(req.Params[1:], " "))
+}
+
+// publish 向主题写入消息, 命令格式: publish <topic> [payload], 请求中的 Key 与 Headers 随消息保存
+// 请求的 Delay 大于0时为延迟消息, 到期之后才分配偏移量, 返回预计的投递时间 deliverAt(毫秒时间戳)
+func (d *Dispatcher) publish(s *Session, req *message.Request) *message.Response {
+	msg := &message.Message{
+		Payload: payload(req),
+		Key:     req.Key,
+		Headers: req.Headers,
+	}
+	topic := d.Broker.Topic(req.Params[0])
+	if req.Delay < 0 || req.Delay > ConstMaxDelay.Milliseconds() {
+		return message.Error(message.CodeBadRequest, fmt.Sprintf("delay 需要在 0 到 %d 毫秒之间", ConstMaxDelay.Milliseconds()))
+	}
+	if req.Delay > 0 {
+		due := topic.PublishDelayed(msg, time.Duration(req.Delay)*time.Millisecond)
+		return message.OK(map[string]int64{"deliverAt": due.UnixNano() / int64(time.Millisecond)})
+	}
+	topic.PublishMessage(msg)
+	return message.OK(map[string]int64{"offset": msg.Offset})
+}
+
+// mpublish 向主题批量写入消息, 命令格式: mpublish <topic>, 消息内容通过请求的 Batch 传递
+// 同一批次的消息分配连续的偏移量, 返回每条消息的偏移量
+func (d *Dispatcher) mpublish(s *Session, req *message.Request) *message.Response {
+	if len(req.Batch) == 0 {
+		return message.Error(message.CodeBadRequest, "批量消息不能为空")
+	}
+	msgs := d.Broker.Topic(req.Params[0]).PublishBatch(req.Batch)
+	offsets := make([]int64, len(msgs))
+	for i, msg := range msgs {
+		offsets[i] = msg.Offset
+	}
+	return message.OK(map[string][]int64{"offsets": offsets})
+}
+
+// subscribe 订阅主题, 命令格式: subscribe <topic> [offset]
+// 指定 offset 时先补发保留的偏移量不小于 offset 的消息, 之后新写入的消息都会推送给客户端
+func (d *Dispatcher) subscribe(s *Session, req *message.Request) *message.Response {
+	topic := req.Params[0]
+	from := int64(-1)
+	if len(req.Params) > 1 {
+		n, err := strconv.ParseInt(req.Params[1], 10, 64)
+		if err != nil || n < 0 {
+			return message.Error(message.CodeBadRequest, "offset 格式错误")
+		}
+		from = n
+	}
+	if s.topics[topic] {
+		return message.OK("already subscribed")
+	}
+	s.topics[topic] = true
+	if !d.Broker.Topic(topic).Subscribe(s, from) {
+		return message.Error(message.CodeServerErr, "补发消息失败, 连接已经断开")
+	}
+	return message.OK("ok")
+}
+
+// unsubscribe 取消订阅, 命令格式: unsubscribe <topic>
+func (d *Dispatcher) unsubscribe(s *Session, req *message.Request) *message.Response {
+	topic := req.Params[0]
+	if s.topics[topic] {
+		delete(s.topics, topic)
+		d.Broker.Topic(topic).Unsubscribe(s)
+	}
+	return message.OK("ok")
+}
@@ -0,0 +1,76 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket 令牌桶
+// 令牌以 rate 个每秒的速度放入桶中, 桶的容量为 burst
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time // 获取当前时间, 测试中可以替换
+}
+
+// NewTokenBucket 创建令牌桶, 初始时桶是满的
+func NewTokenBucket(rate, burst float64) *TokenBucket {
+	return newTokenBucket(rate, burst, time.Now)
+}
+
+func newTokenBucket(rate, burst float64, now func() time.Time) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   now(),
+		now:    now,
+	}
+}
+
+// refill 按照流逝的时间补充令牌
+func (b *TokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// need 取走 n 个令牌需要桶中至少存在的令牌数
+// 超过桶容量的请求只要求桶是满的, 否则大的消息将永远无法通过
+func (b *TokenBucket) need(n float64) float64 {
+	if n > b.burst {
+		return b.burst
+	}
+	return n
+}
+
+// Reserve 预定 n 个令牌, 令牌不足时允许透支, 返回调用方需要等待的时长
+func (b *TokenBucket) Reserve(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.now())
+	wait := b.waitFor(b.need(n))
+	b.tokens -= n
+	return wait
+}
+
+// Peek 返回取走 n 个令牌之前需要等待的时长, 不修改桶中的令牌
+func (b *TokenBucket) Peek(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.now())
+	return b.waitFor(b.need(n))
+}
+
+// waitFor 桶中令牌达到 n 个需要等待的时长
+func (b *TokenBucket) waitFor(n float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
@@ -0,0 +1,167 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// 超出限制之后的处理方式
+const (
+	ModeDelay  = "delay"  // 延迟读取连接中的数据, 直到令牌足够
+	ModeReject = "reject" // 直接返回限流错误以及建议的重试时间
+)
+
+type Config struct {
+	Mode         string `yaml:"mode" json:"mode"`
+	ConnMsgRate  int    `yaml:"connMsgRate" json:"connMsgRate"`
+	ConnByteRate int    `yaml:"connByteRate" json:"connByteRate"`
+	UserMsgRate  int    `yaml:"userMsgRate" json:"userMsgRate"`
+	UserByteRate int    `yaml:"userByteRate" json:"userByteRate"`
+}
+
+// buckets 一组消息数与字节数的令牌桶, 速率为0的桶为 nil 表示不限制
+type buckets struct {
+	msg  *TokenBucket
+	byte *TokenBucket
+}
+
+func newBuckets(msgRate, byteRate int, now func() time.Time) *buckets {
+	b := &buckets{}
+	// 桶容量为1秒的配额, 允许一定程度的突发流量
+	if msgRate > 0 {
+		b.msg = newTokenBucket(float64(msgRate), float64(msgRate), now)
+	}
+	if byteRate > 0 {
+		b.byte = newTokenBucket(float64(byteRate), float64(byteRate), now)
+	}
+	return b
+}
+
+// Limiter 限流器, 管理按用户共享的令牌桶
+type Limiter struct {
+	conf  *Config
+	mu    sync.Mutex
+	users map[string]*buckets
+	gen   int // 配置的版本, 每次 Update 之后加1, 连接发现版本变化时重建令牌桶
+	// rejectMu reject 模式下检查配额与扣减在同一把锁内完成
+	// 否则同一用户的多个连接可能同时通过检查, 之后共同透支用户共享的令牌桶
+	rejectMu sync.Mutex
+	now      func() time.Time // 令牌桶获取当前时间的方法, 测试中可以替换
+}
+
+// New 创建限流器, conf 为 nil 时不做任何限制
+func New(conf *Config) *Limiter {
+	l := &Limiter{now: time.Now}
+	l.Update(conf)
+	return l
+}
+
+// Update 更新限流配置, 所有连接与用户的令牌桶按照新的速率重建, 之前累积的配额不再保留
+func (l *Limiter) Update(conf *Config) {
+	if conf == nil {
+		conf = &Config{}
+	}
+	if conf.Mode != ModeReject {
+		conf.Mode = ModeDelay
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.conf = conf
+	l.users = make(map[string]*buckets)
+	l.gen++
+}
+
+// config 当前的配置以及配置的版本
+func (l *Limiter) config() (*Config, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conf, l.gen
+}
+
+// NewConn 为新建立的连接创建连接级别的限流器
+func (l *Limiter) NewConn() *Conn {
+	conf, gen := l.config()
+	return &Conn{
+		limiter: l,
+		buckets: newBuckets(conf.ConnMsgRate, conf.ConnByteRate, l.now),
+		gen:     gen,
+	}
+}
+
+// user 获取用户共享的令牌桶, 同一个用户的所有连接共用一组配额
+func (l *Limiter) user(name string) *buckets {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.users[name]
+	if !ok {
+		b = newBuckets(l.conf.UserMsgRate, l.conf.UserByteRate, l.now)
+		l.users[name] = b
+	}
+	return b
+}
+
+// Conn 连接级别的限流器, 只在连接的读取协程中使用
+type Conn struct {
+	limiter *Limiter
+	buckets *buckets
+	gen     int
+}
+
+// quota 一次申请在某个令牌桶上需要的令牌数
+type quota struct {
+	bucket *TokenBucket
+	n      float64
+}
+
+// quotas 列出一次请求需要申请配额的所有令牌桶, 以及当前的配置
+func (c *Conn) quotas(user string, count, size int) (*Config, []quota) {
+	conf, gen := c.limiter.config()
+	if gen != c.gen {
+		c.buckets, c.gen = newBuckets(conf.ConnMsgRate, conf.ConnByteRate, c.limiter.now), gen
+	}
+	list := []*buckets{c.buckets}
+	if user != "" {
+		list = append(list, c.limiter.user(user))
+	}
+	var quotas []quota
+	for _, b := range list {
+		if b.msg != nil {
+			quotas = append(quotas, quota{b.msg, float64(count)})
+		}
+		if b.byte != nil {
+			quotas = append(quotas, quota{b.byte, float64(size)})
+		}
+	}
+	return conf, quotas
+}
+
+// Acquire 为一次包含 count 条消息, 共 size 字节的请求申请配额, user 为空表示未认证的连接, 只做连接级别的限制
+// delay 模式下总是返回 true, 调用方需要等待返回的时长之后再继续处理
+// reject 模式下配额不足时返回 false, 以及建议客户端重试的等待时长
+func (c *Conn) Acquire(user string, count, size int) (bool, time.Duration) {
+	conf, quotas := c.quotas(user, count, size)
+	var wait time.Duration
+	if conf.Mode == ModeDelay {
+		for _, q := range quotas {
+			if w := q.bucket.Reserve(q.n); w > wait {
+				wait = w
+			}
+		}
+		return true, wait
+	}
+	// 先确认所有令牌桶的配额都足够, 避免部分扣减
+	c.limiter.rejectMu.Lock()
+	defer c.limiter.rejectMu.Unlock()
+	for _, q := range quotas {
+		if w := q.bucket.Peek(q.n); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+	for _, q := range quotas {
+		q.bucket.Reserve(q.n)
+	}
+	return true, 0
+}
@@ -0,0 +1,129 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock 手动推进的时钟
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+// near 浮点数计算的等待时长允许微小的误差
+func near(got, want time.Duration) bool {
+	d := got - want
+	return d > -time.Microsecond && d < time.Microsecond
+}
+
+func TestTokenBucket(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		peek    bool // true 时只查看不取走令牌
+		n       float64
+		want    time.Duration
+	}
+	cases := []struct {
+		name        string
+		rate, burst float64
+		steps       []step
+	}{
+		{"refill", 10, 10, []step{
+			{0, false, 10, 0},
+			{0, true, 5, 500 * time.Millisecond},
+			{200 * time.Millisecond, true, 5, 300 * time.Millisecond},
+			{300 * time.Millisecond, false, 5, 0},
+			{0, true, 1, 100 * time.Millisecond},
+		}},
+		{"burst cap", 10, 5, []step{
+			{10 * time.Second, false, 5, 0},
+			{0, true, 1, 100 * time.Millisecond},
+		}},
+		{"peek keeps tokens", 10, 10, []step{
+			{0, true, 10, 0},
+			{0, true, 10, 0},
+			{0, false, 10, 0},
+			{0, true, 10, time.Second},
+		}},
+		// 超过容量的请求只要求桶是满的, 之后透支的令牌需要等待补充
+		{"overdraw", 10, 10, []step{
+			{0, false, 15, 0},
+			{0, true, 1, 600 * time.Millisecond},
+			{500 * time.Millisecond, false, 1, 100 * time.Millisecond},
+		}},
+	}
+	for _, c := range cases {
+		clock := &fakeClock{t: time.Unix(1000, 0)}
+		b := newTokenBucket(c.rate, c.burst, clock.now)
+		for i, s := range c.steps {
+			clock.t = clock.t.Add(s.advance)
+			var got time.Duration
+			if s.peek {
+				got = b.Peek(s.n)
+			} else {
+				got = b.Reserve(s.n)
+			}
+			if !near(got, s.want) {
+				t.Errorf("%s step %d: wait = %v, want %v", c.name, i, got, s.want)
+			}
+		}
+	}
+}
+
+func TestConnAcquire(t *testing.T) {
+	type step struct {
+		advance     time.Duration
+		conn        int // 使用的连接
+		user        string
+		count, size int
+		ok          bool
+		wait        time.Duration
+	}
+	cases := []struct {
+		name  string
+		conf  *Config
+		steps []step
+	}{
+		// delay 模式总是放行, 返回需要等待的时长
+		{"delay", &Config{ConnMsgRate: 10}, []step{
+			{0, 0, "", 10, 0, true, 0},
+			{0, 0, "", 5, 0, true, 500 * time.Millisecond},
+			{time.Second, 0, "", 1, 0, true, 0},
+		}},
+		{"reject", &Config{Mode: ModeReject, ConnMsgRate: 10}, []step{
+			{0, 0, "", 10, 0, true, 0},
+			{0, 0, "", 1, 0, false, 100 * time.Millisecond},
+			{100 * time.Millisecond, 0, "", 1, 0, true, 0},
+		}},
+		// 字节数的配额不足时, 消息数的令牌桶不能被扣减
+		{"reject without partial reserve", &Config{Mode: ModeReject, ConnMsgRate: 2, ConnByteRate: 100}, []step{
+			{0, 0, "", 1, 100, true, 0},
+			{0, 0, "", 1, 50, false, 500 * time.Millisecond},
+			{500 * time.Millisecond, 0, "", 2, 50, true, 0},
+		}},
+		// 同一个用户的连接共享配额, 其他用户不受影响
+		{"user shared", &Config{Mode: ModeReject, UserMsgRate: 5}, []step{
+			{0, 0, "alice", 5, 0, true, 0},
+			{0, 1, "alice", 1, 0, false, 200 * time.Millisecond},
+			{0, 1, "bob", 1, 0, true, 0},
+			{0, 1, "", 100, 0, true, 0},
+		}},
+	}
+	for _, c := range cases {
+		clock := &fakeClock{t: time.Unix(1000, 0)}
+		l := &Limiter{now: clock.now}
+		l.Update(c.conf)
+		conns := []*Conn{l.NewConn(), l.NewConn()}
+		for i, s := range c.steps {
+			clock.t = clock.t.Add(s.advance)
+			ok, wait := conns[s.conn].Acquire(s.user, s.count, s.size)
+			if ok != s.ok || !near(wait, s.wait) {
+				t.Errorf("%s step %d: Acquire = %v %v, want %v %v", c.name, i, ok, wait, s.ok, s.wait)
+			}
+		}
+	}
+}
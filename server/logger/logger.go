@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level 日志级别, 低于当前级别的日志不输出
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+// level 当前的日志级别, 重新加载配置时在其他协程中修改
+var level = int32(LevelInfo)
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "unknown"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel 解析配置中的日志级别 debug|info|warn|error, 忽略大小写, 为空时为 info
+func ParseLevel(s string) (Level, error) {
+	if s == "" {
+		return LevelInfo, nil
+	}
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("不支持的日志级别 %s, 需要为 %s", s, strings.Join(levelNames, "|"))
+}
+
+// SetLevel 修改日志级别, 立即生效
+func SetLevel(l Level) {
+	atomic.StoreInt32(&level, int32(l))
+}
+
+// GetLevel 当前的日志级别
+func GetLevel() Level {
+	return Level(atomic.LoadInt32(&level))
+}
+
+func Debug(v ...interface{}) {
+	output(LevelDebug, v)
+}
+
+func Info(v ...interface{}) {
+	output(LevelInfo, v)
+}
+
+func Warn(v ...interface{}) {
+	output(LevelWarn, v)
+}
+
+func Error(v ...interface{}) {
+	output(LevelError, v)
+}
+
+// output 使用标准库的 log 输出, 格式与 log.Println 相同, 开头增加日志级别
+func output(l Level, v []interface{}) {
+	if l < GetLevel() {
+		return
+	}
+	_ = log.Output(3, "["+strings.ToUpper(l.String())+"] "+fmt.Sprintln(v...))
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"github.com/AdeMQ/protocol/message"
+	"github.com/AdeMQ/server/broker"
+	"github.com/AdeMQ/server/limiter"
+)
+
+// configInfo 生成 info 命令返回的配置摘要, 不包含用户密码与证书路径等信息
+func (c *Config) configInfo(b *broker.Broker) *message.ConfigInfo {
+	ret := &message.ConfigInfo{
+		BufLen:     c.BufLen,
+		BufMaxLen:  c.BufMaxLen,
+		LimitMode:  limiter.ModeDelay,
+		LimitRates: map[string]int{},
+	}
+	for _, lc := range c.listeners() {
+		ret.Listeners = append(ret.Listeners, message.ListenerInfo{
+			Network:  lc.Network,
+			Address:  lc.Address,
+			TLS:      lc.TLS != nil,
+			MaxConns: lc.MaxConns,
+		})
+	}
+	if c.Auth != nil {
+		ret.Auth = c.Auth.Enable
+		ret.Users = len(c.Auth.Users)
+		ret.ACLRules = len(c.Auth.ACL)
+	}
+	if l := c.Limit; l != nil {
+		if l.Mode != "" {
+			ret.LimitMode = l.Mode
+		}
+		ret.LimitRates["connMsgRate"] = l.ConnMsgRate
+		ret.LimitRates["connByteRate"] = l.ConnByteRate
+		ret.LimitRates["userMsgRate"] = l.UserMsgRate
+		ret.LimitRates["userByteRate"] = l.UserByteRate
+	}
+	if c.Compression != nil {
+		ret.Codecs = c.Compression.Codecs
+		ret.Threshold = c.Compression.Threshold
+	}
+	bc := b.Config()
+	ret.Retention = bc.Retention
+	ret.AckTimeout = bc.AckTimeout
+	return ret
+}
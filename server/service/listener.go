@@ -0,0 +1,147 @@
+package service
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/AdeMQ/server/logger"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ListenerConfig 监听配置
+type ListenerConfig struct {
+	Network   string     `yaml:"network" json:"network"` // tcp | tcp6 | unix
+	Address   string     `yaml:"address" json:"address"` // 监听地址, unix 类型为 socket 文件路径
+	TLS       *TLSConfig `yaml:"tls" json:"tls"`
+	MaxConns  int        `yaml:"maxConns" json:"maxConns"`   // 最大连接数, 0 表示不限制
+	BufLen    int        `yaml:"bufLen" json:"bufLen"`       // 为 0 时使用服务级别的配置
+	BufMaxLen int        `yaml:"bufMaxLen" json:"bufMaxLen"` // 为 0 时使用服务级别的配置
+	// SocketMode unix socket 文件的权限, 例如 "0660", 通过文件权限控制哪些本地进程可以连接
+	SocketMode string `yaml:"socketMode" json:"socketMode"`
+	// TrustedUser 不为空时, 该监听上的连接无需 auth 握手, 直接视为以该用户认证通过, 访问控制规则依然生效
+	TrustedUser string `yaml:"trustedUser" json:"trustedUser"`
+}
+
+// TLSConfig TLS证书配置
+type TLSConfig struct {
+	CertFile string `yaml:"certFile" json:"certFile"`
+	KeyFile  string `yaml:"keyFile" json:"keyFile"`
+}
+
+// listeners 获取所有监听配置, 未配置 listeners 时使用 address 作为唯一的 TCP 监听
+func (c *Config) listeners() []*ListenerConfig {
+	if len(c.Listeners) > 0 {
+		return c.Listeners
+	}
+	return []*ListenerConfig{{Network: "tcp", Address: c.Address}}
+}
+
+// listen 按照配置开启监听
+func (lc *ListenerConfig) listen() (net.Listener, error) {
+	var (
+		ln  net.Listener
+		err error
+	)
+	switch lc.Network {
+	case "tcp", "tcp4", "tcp6":
+		ln, err = net.Listen(lc.Network, lc.Address)
+	case "unix":
+		ln, err = lc.listenUnix()
+	default:
+		return nil, errors.New("unsupported network " + lc.Network)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lc.TLS != nil {
+		cert, err := tls.LoadX509KeyPair(lc.TLS.CertFile, lc.TLS.KeyFile)
+		if err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
+		ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}})
+	}
+	return ln, nil
+}
+
+// listenUnix 开启 unix socket 监听
+// 配置了 socketMode 时先在权限为 0700 的临时目录中监听并修改权限, 之后再移动到配置的路径
+// 保证 socket 文件出现在配置的路径时已经是配置的权限, 不存在其他进程可以提前连接的时间窗口
+func (lc *ListenerConfig) listenUnix() (net.Listener, error) {
+	// 只清理上次进程异常退出时残留的 socket 文件, 其他类型的文件通常是配置错误, 不能删除
+	if fi, err := os.Lstat(lc.Address); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New(lc.Address + " already exists and is not a socket")
+		}
+		// 可以连接说明还有进程在监听, 不能删除正在使用的 socket 文件
+		if conn, err := net.DialTimeout("unix", lc.Address, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, errors.New("listen unix " + lc.Address + ": address already in use")
+		}
+		if err = os.Remove(lc.Address); err != nil {
+			return nil, err
+		}
+	}
+	if lc.SocketMode == "" {
+		return net.Listen("unix", lc.Address)
+	}
+	mode, err := strconv.ParseUint(lc.SocketMode, 8, 32)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir(filepath.Dir(lc.Address), ".ademq-sock-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "s")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(tmp, os.FileMode(mode)); err == nil {
+		err = os.Rename(tmp, lc.Address)
+	}
+	if err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	// 监听关闭时不再删除临时路径, 由下次启动时清理残留的 socket 文件
+	ln.SetUnlinkOnClose(false)
+	return ln, nil
+}
+
+// serve 循环接受连接, 超出最大连接数的新连接会被直接关闭
+func (lc *ListenerConfig) serve(ln net.Listener, handle func(conn net.Conn)) {
+	var sem chan struct{}
+	if lc.MaxConns > 0 {
+		sem = make(chan struct{}, lc.MaxConns)
+	}
+	for {
+		// 等待客户端建立连接
+		conn, err := ln.Accept()
+		if err != nil {
+			logger.Error("Error accept connect", err.Error())
+			continue
+		}
+		if sem == nil {
+			go handle(conn)
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+			// 开启新的协程处理连接
+			go func() {
+				defer func() { <-sem }()
+				handle(conn)
+			}()
+		default:
+			logger.Warn("Error accept connect 连接数超出上限", lc.Network, lc.Address, conn.RemoteAddr())
+			_ = conn.Close()
+		}
+	}
+}
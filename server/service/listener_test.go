@@ -0,0 +1,81 @@
+package service
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ademq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := filepath.Join(dir, "mq.sock")
+
+	// 配置的路径是普通文件时不能删除
+	if err = ioutil.WriteFile(addr, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lc := &ListenerConfig{Network: "unix", Address: addr, SocketMode: "0660"}
+	if _, err = lc.listen(); err == nil {
+		t.Fatal("listen on a regular file should fail")
+	}
+	if b, _ := ioutil.ReadFile(addr); string(b) != "data" {
+		t.Fatal("regular file was modified")
+	}
+	_ = os.Remove(addr)
+
+	ln, err := lc.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	fi, err := os.Stat(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != 0660 {
+		t.Errorf("mode = %v, want socket 0660", fi.Mode())
+	}
+	// 仍在监听的 socket 文件不能被替换
+	if ln2, err := lc.listen(); err == nil {
+		_ = ln2.Close()
+		t.Fatal("listen on a live socket should fail")
+	}
+	if conn, err := net.Dial("unix", addr); err != nil {
+		t.Fatalf("live socket was removed: %v", err)
+	} else {
+		_ = conn.Close()
+	}
+}
+
+func TestListenUnixStaleSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ademq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := filepath.Join(dir, "mq.sock")
+
+	// 模拟进程异常退出之后残留的 socket 文件
+	old, err := net.ListenUnix("unix", &net.UnixAddr{Name: addr, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.SetUnlinkOnClose(false)
+	_ = old.Close()
+	if _, err = os.Lstat(addr); err != nil {
+		t.Fatal(err)
+	}
+
+	lc := &ListenerConfig{Network: "unix", Address: addr}
+	ln, err := lc.listen()
+	if err != nil {
+		t.Fatalf("listen on a stale socket: %v", err)
+	}
+	_ = ln.Close()
+}
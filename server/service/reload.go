@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/AdeMQ/protocol/packet"
+	"github.com/AdeMQ/server/auth"
+	"github.com/AdeMQ/server/handler"
+	"github.com/AdeMQ/server/limiter"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// server 运行中的服务, 重新加载配置时更新
+type server struct {
+	mu         sync.RWMutex
+	conf       *Config
+	dispatcher *handler.Dispatcher
+}
+
+var running = &server{}
+
+func (s *server) start(conf *Config, dispatcher *handler.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conf, s.dispatcher = conf, dispatcher
+}
+
+// config 当前生效的配置
+func (s *server) config() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.conf
+}
+
+// Reload 按照新的配置更新运行中的服务, prev 为上一次加载的配置, 用于找出可以立即生效的配置中发生变化的项
+// 缓冲区大小、认证与访问控制、限流以及消息配置立即生效, 监听地址与压缩配置需要重启服务才能生效
+// 返回立即生效以及需要重启的配置项名称, 配置校验失败时不做任何修改
+func Reload(prev, next *Config) (applied, restart []string, err error) {
+	if err = next.Validate(); err != nil {
+		return nil, nil, err
+	}
+	running.mu.Lock()
+	defer running.mu.Unlock()
+	if running.dispatcher == nil {
+		return nil, nil, errors.New("服务尚未启动")
+	}
+	d := running.dispatcher
+	changed := func(name string, a, b interface{}) bool {
+		if reflect.DeepEqual(a, b) {
+			return false
+		}
+		applied = append(applied, name)
+		return true
+	}
+	// 需要重启的配置项继续使用当前的值, 保证 info 命令显示的是实际生效的配置
+	conf := *running.conf
+	// 缓冲区配置在建立连接时读取, 只对之后建立的连接生效
+	if changed("server.bufLen", prev.BufLen, next.BufLen) {
+		conf.BufLen = next.BufLen
+	}
+	if changed("server.bufMaxLen", prev.BufMaxLen, next.BufMaxLen) {
+		conf.BufMaxLen = next.BufMaxLen
+	}
+	if changed("server.auth", prev.Auth, next.Auth) {
+		d.Auth.Update(next.Auth)
+		conf.Auth = next.Auth
+	}
+	if changed("server.limit", prev.Limit, next.Limit) {
+		d.Limiter.Update(next.Limit)
+		conf.Limit = next.Limit
+	}
+	if changed("server.broker", prev.Broker, next.Broker) {
+		d.Broker.Update(next.Broker)
+		conf.Broker = next.Broker
+	}
+	// 需要重启的配置项与实际生效的配置比较, 修改之后又改回原值时不再提示
+	for name, pair := range map[string][2]interface{}{
+		"server.address":     {conf.Address, next.Address},
+		"server.listeners":   {conf.Listeners, next.Listeners},
+		"server.compression": {conf.Compression, next.Compression},
+	} {
+		if !reflect.DeepEqual(pair[0], pair[1]) {
+			restart = append(restart, name)
+		}
+	}
+	sort.Strings(restart)
+	running.conf = &conf
+	return applied, restart, nil
+}
+
+// Validate 校验配置, 启动服务以及重新加载配置之前调用, 避免应用错误的配置
+func (c *Config) Validate() error {
+	if c.BufLen < 0 || c.BufMaxLen < 0 {
+		return errors.New("bufLen 与 bufMaxLen 不能为负数")
+	}
+	if c.BufLen > 0 && c.BufMaxLen > 0 && c.BufMaxLen < c.BufLen {
+		return errors.New("bufMaxLen 不能小于 bufLen")
+	}
+	for _, lc := range c.listeners() {
+		switch lc.Network {
+		case "tcp", "tcp4", "tcp6", "unix":
+		default:
+			return fmt.Errorf("listeners: 不支持的 network %s", lc.Network)
+		}
+		if lc.MaxConns < 0 || lc.BufLen < 0 || lc.BufMaxLen < 0 {
+			return fmt.Errorf("listeners: %s 的 maxConns 与缓冲区配置不能为负数", lc.Address)
+		}
+	}
+	if a := c.Auth; a != nil {
+		for _, u := range a.Users {
+			if u == nil || u.Name == "" || len(u.Hash) != 64 {
+				return errors.New("auth.users: 用户名不能为空, hash 需要为 sha256 的十六进制")
+			}
+		}
+		for _, r := range a.ACL {
+			if r == nil || r.User == "" || r.Name == "" {
+				return errors.New("auth.acl: user 与 name 不能为空")
+			}
+			if r.Resource != "*" && r.Resource != auth.ResourceTopic && r.Resource != auth.ResourceQueue {
+				return fmt.Errorf("auth.acl: 不支持的 resource %s", r.Resource)
+			}
+			for _, p := range r.Perms {
+				if p != auth.PermPublish && p != auth.PermConsume && p != auth.PermAdmin {
+					return fmt.Errorf("auth.acl: 不支持的权限 %s", p)
+				}
+			}
+		}
+	}
+	if l := c.Limit; l != nil {
+		if l.Mode != "" && l.Mode != limiter.ModeDelay && l.Mode != limiter.ModeReject {
+			return fmt.Errorf("limit.mode: 不支持的模式 %s", l.Mode)
+		}
+		if l.ConnMsgRate < 0 || l.ConnByteRate < 0 || l.UserMsgRate < 0 || l.UserByteRate < 0 {
+			return errors.New("limit: 速率不能为负数")
+		}
+	}
+	if cc := c.Compression; cc != nil {
+		for _, name := range cc.Codecs {
+			if packet.CodecByName(name) == nil {
+				return fmt.Errorf("compression.codecs: 不支持的压缩算法 %s", name)
+			}
+		}
+	}
+	if b := c.Broker; b != nil && (b.Retention < 0 || b.AckTimeout < 0) {
+		return errors.New("broker: retention 与 ackTimeout 不能为负数")
+	}
+	return nil
+}
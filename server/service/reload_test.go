@@ -0,0 +1,52 @@
+package service
+
+import (
+	"github.com/AdeMQ/server/auth"
+	"github.com/AdeMQ/server/broker"
+	"github.com/AdeMQ/server/handler"
+	"github.com/AdeMQ/server/limiter"
+	"reflect"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	conf := &Config{Address: ":10601", BufLen: 1, BufMaxLen: 1024, Broker: &broker.Config{Retention: 100}}
+	prev := *conf
+	d := handler.NewDispatcher(auth.New(conf.Auth), limiter.New(conf.Limit), conf.Compression, broker.New(conf.Broker))
+	running.start(conf, d)
+	defer running.start(nil, nil)
+
+	next := &Config{
+		Address:   ":10602",
+		BufLen:    1,
+		BufMaxLen: 2048,
+		Limit:     &limiter.Config{Mode: limiter.ModeReject, ConnMsgRate: 10},
+		Broker:    &broker.Config{Retention: 5},
+	}
+	applied, restart, err := Reload(&prev, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"server.bufMaxLen", "server.limit", "server.broker"}; !reflect.DeepEqual(applied, want) {
+		t.Errorf("applied = %v, want %v", applied, want)
+	}
+	if want := []string{"server.address"}; !reflect.DeepEqual(restart, want) {
+		t.Errorf("restart = %v, want %v", restart, want)
+	}
+	if got := d.Broker.Config().Retention; got != 5 {
+		t.Errorf("retention = %d, want 5", got)
+	}
+	if c := running.config(); c.Address != ":10601" || c.BufMaxLen != 2048 {
+		t.Errorf("running config = %s %d, want :10601 2048", c.Address, c.BufMaxLen)
+	}
+
+	// 校验失败时不做任何修改
+	bad := *next
+	bad.Limit = &limiter.Config{Mode: "bogus"}
+	if _, _, err = Reload(next, &bad); err == nil {
+		t.Error("Reload with invalid limit mode should fail")
+	}
+	if c := running.config(); c.Limit.Mode != limiter.ModeReject {
+		t.Errorf("limit mode = %s, want %s", c.Limit.Mode, limiter.ModeReject)
+	}
+}
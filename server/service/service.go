@@ -1,42 +1,76 @@
 package service
 
 import (
-	"fmt"
+	"github.com/AdeMQ/protocol/message"
 	"github.com/AdeMQ/protocol/packet"
-	"log"
+	"github.com/AdeMQ/server/auth"
+	"github.com/AdeMQ/server/broker"
+	"github.com/AdeMQ/server/handler"
+	"github.com/AdeMQ/server/limiter"
+	"github.com/AdeMQ/server/logger"
 	"net"
+	"sync"
 )
 
 type Config struct {
-	Address   string `yaml:"address" json:"address"`
-	BufLen    int    `yaml:"bufLen" json:"bufLen"`
-	BufMaxLen int    `yaml:"bufMaxLen" json:"bufMaxLen"`
+	Address   string          `yaml:"address" json:"address"`
+	BufLen    int             `yaml:"bufLen" json:"bufLen"`
+	BufMaxLen int             `yaml:"bufMaxLen" json:"bufMaxLen"`
+	Auth      *auth.Config    `yaml:"auth" json:"auth"`
+	Limit     *limiter.Config `yaml:"limit" json:"limit"`
+	// Compression 压缩配置, 客户端连接时通过 hello 命令协商
+	Compression *packet.CompressConfig `yaml:"compression" json:"compression"`
+	Broker      *broker.Config         `yaml:"broker" json:"broker"`
+	// Listeners 监听列表, 为空时只监听 Address 对应的 TCP 地址
+	Listeners []*ListenerConfig `yaml:"listeners" json:"listeners"`
 }
 
 // Run 启动服务
 func Run(conf *Config) (err error) {
-
-	// 开启TCP的端口监听
-	ln, err := net.Listen("tcp", conf.Address)
-	if err != nil {
-		log.Println("Error start listen", err.Error())
-		return
+	if err = conf.Validate(); err != nil {
+		logger.Error("Error invalid config", err.Error())
+		return err
 	}
-	for {
-		// 等待客户端建立连接
-		conn, err := ln.Accept()
+
+	// 先开启所有的端口监听, 任意一个失败都不启动服务
+	listeners := conf.listeners()
+	lns := make([]net.Listener, 0, len(listeners))
+	for _, lc := range listeners {
+		ln, err := lc.listen()
 		if err != nil {
-			log.Println("Error accept connect", err.Error())
-			continue
+			logger.Error("Error start listen", lc.Network, lc.Address, err.Error())
+			for _, opened := range lns {
+				_ = opened.Close()
+			}
+			return err
 		}
-		// 开启新的协程处理连接
-		go handleConnection(conn, conf)
+		logger.Info("Listen", lc.Network, lc.Address, "tls", lc.TLS != nil)
+		lns = append(lns, ln)
 	}
-
+	// 所有连接共用同一个命令分发器
+	dispatcher := handler.NewDispatcher(auth.New(conf.Auth), limiter.New(conf.Limit), conf.Compression, broker.New(conf.Broker))
+	dispatcher.ConfigInfo = func() *message.ConfigInfo {
+		return running.config().configInfo(dispatcher.Broker)
+	}
+	running.start(conf, dispatcher)
+	var wg sync.WaitGroup
+	for i, lc := range listeners {
+		lc, ln := lc, lns[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lc.serve(ln, func(conn net.Conn) {
+				// 重新加载配置之后, 新建立的连接使用新的缓冲区配置
+				handleConnection(conn, running.config(), lc, dispatcher)
+			})
+		}()
+	}
+	wg.Wait()
+	return nil
 }
 
 // 连接处理函数
-func handleConnection(conn net.Conn, conf *Config) {
+func handleConnection(conn net.Conn, conf *Config, lc *ListenerConfig, dispatcher *handler.Dispatcher) {
 	defer closeConnection(conn)
 
 	// TCP数据包边界问题（俗称TCP粘包问题）
@@ -44,30 +78,46 @@ func handleConnection(conn net.Conn, conf *Config) {
 	// 这个问题只能通过上层的应用协议栈设计来解决，根据业界的主流协议的解决方案，一般有三种：
 	// 		消息定长、设置消息边界、将消息分为消息头和消息体
 
+	// 监听级别的缓冲区配置优先
+	bufLen, bufMaxLen := conf.BufLen, conf.BufMaxLen
+	if lc.BufLen > 0 {
+		bufLen = lc.BufLen
+	}
+	if lc.BufMaxLen > 0 {
+		bufMaxLen = lc.BufMaxLen
+	}
+
 	// 这里我们将采用消息头+消息体的方法来 确定消息边界
 	// TODO 边界界定，获取完整消息之后触发完整的 receive 事件，相应的，之后给客户端回传消息也将采用该方式处理
 	// 客户端回收结果也会是类似的处理方式
 	var (
 		// TcpConn 实现了 io.reader 接口，我们可以用自己封装的 buffer 来处理
-		tcpConn     = packet.New(conn, conf.BufLen*1024, conf.BufMaxLen*1024)
+		tcpConn     = packet.New(conn, bufLen*1024, bufMaxLen*1024)
 		headBuf     []byte
 		contentSize int
 		contentBuf  []byte
+		session     = dispatcher.NewSession(tcpConn)
 	)
+	if lc.TrustedUser != "" {
+		session.User = lc.TrustedUser
+		session.Authenticated = true
+	}
 
 	defer tcpConn.Close()
+	defer dispatcher.CloseSession(session)
 
 	// 开启向该连接发送消息的协程, 阻塞监听消息, 如果连接关闭，则退出
-	// go handleWriteConnection(tcpConn)
+	// 服务端主动推送的消息通过该协程发送, 请求的响应依然在读取协程中直接回写
+	go handleWriteConnection(tcpConn)
 
 	// 循环阻塞读取消息, 读取到的消息追加存储到消息体中, 待消息收满之后, 发送给程序处理
 	for {
 		_, err := tcpConn.ReadFromConn()
 		if err != nil {
-			log.Println("Error reading", err.Error())
+			logger.Info("Error reading", err.Error())
 			if err.Error() == packet.ConstBufferFullErr {
-				// 因为需要立即返回，此处就直接发送到连接中
-				_ = tcpConn.SendMessageDirect([]byte(packet.ConstBufferFullErr))
+				// 超长的消息没有读取完整, 无法得到请求编号, 返回不带编号的错误响应之后关闭连接
+				_ = tcpConn.SendMessageDirect(message.Error(message.CodeBadRequest, packet.ConstBufferFullErr).Encode())
 			}
 			return
 		}
@@ -84,10 +134,19 @@ func handleConnection(conn net.Conn, conf *Config) {
 			// 如果缓冲区中的内容长度超过或者等于 消息头+消息体长度，那么后面相当于读取到了消息体的消息
 			if tcpConn.ReadBufLen() >= contentSize+packet.ConstHeadSize {
 				// 将完整的消息体内容读取到缓冲区，进行后续处理
-				// TODO 分发数据并处理，处理过程中涉及到的消息返回需要设计整体架构
 				contentBuf = tcpConn.Read(packet.ConstHeadSize, contentSize)
-				// 数据回写（ 读-写阻塞模型）
-				fmt.Println(string(contentBuf))
+				if contentBuf, err = tcpConn.Decompress(headBuf, contentBuf); err != nil {
+					logger.Warn("Error reading", err.Error())
+					_ = tcpConn.SendMessageDirect(message.Error(message.CodeBadRequest, err.Error()).Encode())
+					return
+				}
+				// 分发数据并处理, 结果直接回写（ 读-写阻塞模型）
+				if resp := dispatcher.Dispatch(session, contentBuf); resp != nil {
+					if err = tcpConn.SendMessageDirect(resp.Encode()); err != nil {
+						logger.Warn("Error Writing 消息发送失败", err.Error())
+						return
+					}
+				}
 				continue
 			}
 			break
@@ -107,14 +166,14 @@ func handleWriteConnection(tcpConn *packet.TcpConn) {
 		case msg := <-tcpConn.WritableEventChan:
 			if msg == nil {
 				// chan关闭了
-				log.Println("Error Writing 连接已经关闭")
+				logger.Debug("Error Writing 连接已经关闭")
 				return
 			}
 			if tcpConn.Closed {
 				goto End
 			}
 			if err := tcpConn.SendMessageDirect(msg); err != nil {
-				log.Println("Error Writing 消息发送失败", err.Error())
+				logger.Warn("Error Writing 消息发送失败", err.Error())
 			}
 		}
 	}